@@ -55,6 +55,13 @@ var (
 )
 var log = logf.Log.WithName("cmd")
 
+// commands are run instead of the operator when they are the first argument
+var commands = map[string]func(args []string) int{
+	redactCommand: runRedact,
+	signCommand:   runSign,
+	verifyCommand: runVerify,
+}
+
 func printVersion() {
 	log.Info(fmt.Sprintf("Operator Version: %s", version.Version))
 	log.Info(fmt.Sprintf("Go Version: %s", runtime.Version()))
@@ -63,6 +70,13 @@ func printVersion() {
 }
 
 func main() {
+	// The redaction and signing jobs run the operator binary with a command
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:]))
+		}
+	}
+
 	// Add the zap logger flag set to the CLI. The flag set must
 	// be added before calling pflag.Parse().
 	pflag.CommandLine.AddFlagSet(zap.FlagSet())
@@ -0,0 +1,85 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	"github.com/IBM/ibm-healthcheck-operator/pkg/redact"
+)
+
+// redactCommand is the command of the operator binary run by the redaction jobs of the must gather jobs
+const redactCommand = "redact"
+
+// runRedact redacts the gathered data with the policy, and writes the counts to the result file
+func runRedact(args []string) int {
+	flags := flag.NewFlagSet(redactCommand, flag.ContinueOnError)
+	policyFile := flags.String("policy", "", "file of the redaction policy, as JSON")
+	job := flags.String("artifact", "", "name of the gather job whose data is redacted in the directory")
+	resultFile := flags.String("result", "/dev/termination-log", "file the counts of the redacted data are written to, as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s --policy FILE --artifact JOB DIR\n", os.Args[0], redactCommand)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *policyFile == "" || *job == "" || flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	if err := redactData(*policyFile, *job, flags.Arg(0), *resultFile); err != nil {
+		fmt.Fprintf(os.Stderr, "redact: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func redactData(policyFile, job, dir, resultFile string) error {
+	data, err := ioutil.ReadFile(policyFile)
+	if err != nil {
+		return err
+	}
+	policy := &operatorv1alpha1.Redaction{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return fmt.Errorf("invalid policy %s: %v", policyFile, err)
+	}
+	redactor, err := redact.New(policy)
+	if err != nil {
+		return err
+	}
+
+	result, err := redactor.RedactData(dir, job)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("redacted %d of %d files: %d rule matches, %d keys, %d resources removed, %d files skipped\n",
+		result.FilesRedacted, result.FilesScanned, result.RuleMatches, result.KeysRedacted, result.ResourcesRemoved,
+		result.FilesSkipped)
+
+	out, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(resultFile, out, 0644)
+}
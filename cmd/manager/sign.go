@@ -0,0 +1,132 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/IBM/ibm-healthcheck-operator/pkg/integrity"
+)
+
+const (
+	// signCommand is the command of the operator binary run by the signing jobs of the must gather jobs
+	signCommand = "sign"
+	// verifyCommand checks a downloaded archive against its signed manifest, offline
+	verifyCommand = "verify"
+)
+
+// runSign archives the gathered data, writes its manifest signed with the key next to the archive,
+// and writes the digests to the result file
+func runSign(args []string) int {
+	flags := flag.NewFlagSet(signCommand, flag.ContinueOnError)
+	keyFile := flags.String("key", "", "file of the PEM encoded ed25519 private key")
+	job := flags.String("artifact", "", "name of the gather job whose data is signed in the directory")
+	resultFile := flags.String("result", "/dev/termination-log", "file the digests of the archive are written to, as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s --key FILE --artifact JOB DIR\n", os.Args[0], signCommand)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *keyFile == "" || *job == "" || flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	if err := signData(*keyFile, *job, flags.Arg(0), *resultFile); err != nil {
+		fmt.Fprintf(os.Stderr, "sign: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func signData(keyFile, job, dir, resultFile string) error {
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return err
+	}
+	key, err := integrity.ParsePrivateKey(data)
+	if err != nil {
+		return fmt.Errorf("invalid key %s: %v", keyFile, err)
+	}
+
+	archive, err := integrity.ArchiveData(dir, job)
+	if err != nil {
+		return err
+	}
+	m, err := integrity.SignArchive(archive, key)
+	if err != nil {
+		return err
+	}
+	result := integrity.Result{
+		Archive:        m.Archive,
+		Manifest:       filepath.Base(integrity.ManifestPath(archive)),
+		Signature:      filepath.Base(integrity.SignaturePath(archive)),
+		Digest:         m.Digest,
+		Files:          int32(len(m.Files)),
+		KeyFingerprint: integrity.Fingerprint(key.Public().(ed25519.PublicKey)),
+	}
+	fmt.Printf("signed the manifest of %d files of %s, %s\n", result.Files, result.Archive, result.Digest)
+
+	out, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(resultFile, out, 0644)
+}
+
+// runVerify checks the signature of the manifest of the archive and the archive against the manifest
+func runVerify(args []string) int {
+	flags := flag.NewFlagSet(verifyCommand, flag.ContinueOnError)
+	keyFile := flags.String("key", "", "file of the PEM encoded ed25519 public key")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s --key FILE ARCHIVE\n", os.Args[0], verifyCommand)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *keyFile == "" || flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	data, err := ioutil.ReadFile(*keyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verify: %v\n", err)
+		return 1
+	}
+	key, err := integrity.ParsePublicKey(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verify: invalid key %s: %v\n", *keyFile, err)
+		return 1
+	}
+	m, err := integrity.Verify(flags.Arg(0), key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verify: %v\n", err)
+		return 1
+	}
+	fmt.Printf("%s: OK, %d files, %s, manifest created %s\n", flags.Arg(0), len(m.Files), m.Digest, m.Created)
+	return 0
+}
@@ -0,0 +1,58 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CloudPakSpec defines the desired state of CloudPak
+type CloudPakSpec struct {
+	// FullName is the display name of the CloudPak
+	FullName string `json:"fullName"`
+	// ShortName is the short name of the CloudPak, unique across the cluster
+	ShortName string `json:"shortName"`
+	// ID is the product id of the CloudPak, unique across the cluster
+	ID string `json:"id"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CloudPak registers a CloudPak with the health service. The CloudPaks are installed by the product
+// operators and aggregated into the cpnames of every HealthService.
+// +kubebuilder:resource:path=cloudpaks,scope=Cluster,shortName=cp
+// +kubebuilder:printcolumn:name="Short Name",type=string,JSONPath=`.spec.shortName`
+// +kubebuilder:printcolumn:name="ID",type=string,JSONPath=`.spec.id`
+type CloudPak struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CloudPakSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CloudPakList contains a list of CloudPak
+type CloudPakList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CloudPak `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CloudPak{}, &CloudPakList{})
+}
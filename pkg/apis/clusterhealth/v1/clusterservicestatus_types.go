@@ -0,0 +1,76 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ServiceNameLabel is the label holding the name of the service
+	ServiceNameLabel = "clusterhealth.ibm.com/service-name"
+	// ServiceVersionLabel is the label holding the version of the service
+	ServiceVersionLabel = "clusterhealth.ibm.com/service-version"
+)
+
+const (
+	// StateRunning means all the pods of the service are running and ready
+	StateRunning = "Running"
+	// StatePending means some pods of the service are not ready yet
+	StatePending = "Pending"
+	// StateFailed means some pods of the service are failing
+	StateFailed = "Failed"
+)
+
+// ClusterServiceStatusStatus defines the observed state of ClusterServiceStatus
+type ClusterServiceStatusStatus struct {
+	// CurrentState is the state of the service, one of Running, Pending or Failed
+	CurrentState string `json:"currentState,omitempty"`
+	// RestartCount is the total number of container restarts of the service pods
+	RestartCount int32 `json:"restartCount,omitempty"`
+	// StatusDependencies are the names of the services this service depends on
+	StatusDependencies []string `json:"statusDependencies,omitempty"`
+	// PodFailureStatus maps the name of every failing pod of the service to the failure reason
+	PodFailureStatus map[string]string `json:"podFailureStatus,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterServiceStatus is the Schema for the clusterservicestatuses API
+// +kubebuilder:resource:path=clusterservicestatuses,scope=Cluster,shortName=css
+// +kubebuilder:printcolumn:name="Service Name",type=string,JSONPath=`.metadata.labels.clusterhealth\.ibm\.com/service-name`
+// +kubebuilder:printcolumn:name="Service Version",type=string,JSONPath=`.metadata.labels.clusterhealth\.ibm\.com/service-version`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.currentState`
+type ClusterServiceStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status ClusterServiceStatusStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterServiceStatusList contains a list of ClusterServiceStatus
+type ClusterServiceStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterServiceStatus `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterServiceStatus{}, &ClusterServiceStatusList{})
+}
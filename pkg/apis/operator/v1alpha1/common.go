@@ -18,10 +18,154 @@ package v1alpha1
 
 // image defines the desired image repository, tag and imagepullpolicy
 type Image struct {
-	// image repository, default is empty
-	Repository string `json:"repository"`
-	// image tag, default is empty
-	Tag string `json:"tag"`
-	// image pull policy, default is IfNotPresent
+	// image repository, it overrides the repository of the image set in the operator, default is empty
+	Repository string `json:"repository,omitempty"`
+	// image tag, it overrides the tag of the image set in the operator, default is empty
+	Tag string `json:"tag,omitempty"`
+	// image digest, such as sha256:<hex>, it is preferred to the tag, default is empty
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`
+	Digest string `json:"digest,omitempty"`
+	// image pull policy, one of Always, IfNotPresent or Never, default is IfNotPresent,
+	// or Always for an image with the latest tag or no tag
 	PullPolicy string `json:"pullPolicy,omitempty"`
 }
+
+// Ingress defines the Ingress which exposes the service
+type Ingress struct {
+	// disable the Ingress, an existing Ingress is deleted, default is false
+	Disabled bool `json:"disabled,omitempty"`
+	// ingress class name, default is the ibm-icp-management class with its rewrite and header annotations
+	IngressClassName string `json:"ingressClassName,omitempty"`
+	// hosts of the Ingress rules, default is any host
+	Hosts []string `json:"hosts,omitempty"`
+	// name of the secret which holds the TLS certificate of the hosts, default is no TLS
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+	// path of the service, default is /cluster-health/ for the health service and /must-gather/ for the must gather service
+	Path string `json:"path,omitempty"`
+	// extra Ingress annotations, they override the default annotations with the same name
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ExposureMode is the kind of object which exposes the service outside of the cluster
+type ExposureMode string
+
+const (
+	// ExposureAuto uses a Route when route.openshift.io is served, else an HTTPRoute when
+	// gateway.networking.k8s.io is served, else an Ingress
+	ExposureAuto ExposureMode = "Auto"
+	// ExposureIngress exposes the service with a networking.k8s.io Ingress
+	ExposureIngress ExposureMode = "Ingress"
+	// ExposureRoute exposes the service with an OpenShift route.openshift.io Route
+	ExposureRoute ExposureMode = "Route"
+	// ExposureHTTPRoute exposes the service with a Gateway API gateway.networking.k8s.io HTTPRoute
+	ExposureHTTPRoute ExposureMode = "HTTPRoute"
+)
+
+// Exposure defines how the service is exposed outside of the cluster.
+// The path of the Route and HTTPRoute is the ingress path.
+type Exposure struct {
+	// exposure mode, one of Auto, Ingress, Route or HTTPRoute, default is Ingress
+	// +kubebuilder:validation:Enum=Auto;Ingress;Route;HTTPRoute
+	Mode ExposureMode `json:"mode,omitempty"`
+	// Route defines the OpenShift Route, used in Route mode
+	Route RouteExposure `json:"route,omitempty"`
+	// HTTPRoute defines the Gateway API HTTPRoute, used in HTTPRoute mode
+	HTTPRoute HTTPRouteExposure `json:"httpRoute,omitempty"`
+}
+
+// RouteExposure defines the OpenShift Route of the service
+type RouteExposure struct {
+	// route host, default is generated by the router
+	Host string `json:"host,omitempty"`
+	// TLS termination, one of edge, reencrypt or passthrough, default is no TLS
+	// +kubebuilder:validation:Enum=edge;reencrypt;passthrough
+	Termination string `json:"termination,omitempty"`
+}
+
+// HTTPRouteExposure defines the Gateway API HTTPRoute of the service
+type HTTPRouteExposure struct {
+	// gateways the HTTPRoute attaches to
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+	// hostnames of the HTTPRoute, default is any hostname accepted by the gateways
+	Hostnames []string `json:"hostnames,omitempty"`
+}
+
+// ParentReference identifies a Gateway
+type ParentReference struct {
+	// gateway name
+	Name string `json:"name"`
+	// gateway namespace, default is the namespace of the HTTPRoute
+	Namespace string `json:"namespace,omitempty"`
+	// name of the gateway listener, default is all listeners
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// Upload defines the S3 compatible object storage the gathered data is uploaded to, as a
+// compressed tar archive. The objects are addressed in path style, as MinIO serves them.
+type Upload struct {
+	// object storage endpoint, such as https://minio.example.com:9000
+	// +kubebuilder:validation:Pattern=`^https?://[^/?#]+$`
+	Endpoint string `json:"endpoint"`
+	// bucket name
+	// +kubebuilder:validation:Pattern=`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`
+	Bucket string `json:"bucket"`
+	// prefix of the object names, such as must-gather/cluster1, default is no prefix
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9._/-]*$`
+	Prefix string `json:"prefix,omitempty"`
+	// region of the bucket, default is us-east-1
+	Region string `json:"region,omitempty"`
+	// name of the secret which holds the accessKeyID and the secretAccessKey
+	CredentialsSecretName string `json:"credentialsSecretName"`
+	// TLS defines how the endpoint certificate is verified
+	TLS UploadTLS `json:"tls,omitempty"`
+}
+
+// UploadTLS defines how the certificate of the object storage endpoint is verified
+type UploadTLS struct {
+	// name of the secret which holds the ca.crt the endpoint certificate is verified with,
+	// default is the CAs of the image
+	CASecretName string `json:"caSecretName,omitempty"`
+	// skip the verification of the endpoint certificate, default is false
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+const (
+	// ConditionReady indicates that all the operands are available
+	ConditionReady = "Ready"
+	// ConditionProgressing indicates that the operands are being created or rolled out
+	ConditionProgressing = "Progressing"
+	// ConditionDegraded indicates that the operands failed to reconcile or are unavailable
+	ConditionDegraded = "Degraded"
+	// ConditionConfigValid indicates that the configuration shipped with the operator is loaded
+	ConditionConfigValid = "ConfigValid"
+)
+
+// Phase is a one word summary of the status conditions
+type Phase string
+
+const (
+	// PhaseRunning means all the operands are ready
+	PhaseRunning Phase = "Running"
+	// PhaseProgressing means the operands are being created or rolled out
+	PhaseProgressing Phase = "Progressing"
+	// PhaseDegraded means the operands failed to reconcile or are unavailable
+	PhaseDegraded Phase = "Degraded"
+)
+
+// PodStatus is the observed state of an operand pod
+type PodStatus struct {
+	// Name is the name of the pod
+	Name string `json:"name"`
+	// Phase is the phase of the pod, one of Pending, Running, Succeeded, Failed or Unknown
+	Phase string `json:"phase,omitempty"`
+	// Ready is true when the pod passes its readiness probes
+	Ready bool `json:"ready"`
+	// RestartCount is the total number of container restarts of the pod
+	RestartCount int32 `json:"restartCount"`
+	// NodeName is the node the pod is scheduled on
+	NodeName string `json:"nodeName,omitempty"`
+	// LastTerminationReason is the reason of the last container termination, such as OOMKilled or Error
+	LastTerminationReason string `json:"lastTerminationReason,omitempty"`
+	// ImageDigest is the digest of the image run by the main container
+	ImageDigest string `json:"imageDigest,omitempty"`
+}
@@ -26,19 +26,21 @@ import (
 
 // HealthServiceSpecMemcached defines the desired state of HealthService.Memcached
 type HealthServiceSpecMemcached struct {
-	// memcached deployment name
-	Name string `json:"name,"`
-	// deprecated, define image in operator.yaml
+	// memcached deployment and service name, default is the HealthService name with a "-memcached" suffix
+	Name string `json:"name,omitempty"`
+	// memcached image, it overrides the ICP_MEMCACHED_IMAGE of the operator
 	Image Image `json:"image,omitempty"`
 	// memcached pod replicas, default is 1
 	Replicas int32 `json:"replicas,omitempty"`
-	// memcached deployment ServiceAccountName, default is default
+	// memcached deployment ServiceAccountName, default is ibm-healthcheck-operator-cluster
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
 	// memcached deployment node selector, default is empty
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
-	// memcached deployment tolerations, default is empty
+	// memcached deployment tolerations, added to the default dedicated and CriticalAddonsOnly tolerations.
+	// A toleration with the same key and effect as a default one replaces it
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
-	// memcached deployment security context, default is empty
+	// memcached container security context, every field set here overrides the default
+	// restricted security context of the container
 	SecurityContext corev1.SecurityContext `json:"securityContext,omitempty"`
 	// memcached startup command, default value is "memcached -m 64 -o modern -v"
 	Command []string `json:"command,omitempty"`
@@ -48,9 +50,9 @@ type HealthServiceSpecMemcached struct {
 
 // HealthServiceSpecHealthService defines the desired state of HealthService.HealthService
 type HealthServiceSpecHealthService struct {
-	// health service deployment name
-	Name string `json:"name"`
-	// deprecated, define image in operator.yaml
+	// health service deployment, service and ingress name, default is the HealthService name
+	Name string `json:"name,omitempty"`
+	// health service image, it overrides the SYSTEM_HEALTHCHECK_SERVICE_IMAGE of the operator
 	Image Image `json:"image,omitempty"`
 	// configmap which contains health srevice configuration files, deprecated
 	ConfigmapName string `json:"configmapName"`
@@ -58,17 +60,21 @@ type HealthServiceSpecHealthService struct {
 	CloudpakNameSetting string `json:"cloudpakNameSetting,omitempty"`
 	// set labels/annotation name to get pod's servicename
 	ServiceNameSetting string `json:"serviceNameSetting,omitempty"`
+	// set labels/annotation name to get pod's service version, default is Annotations:productVersion
+	ServiceVersionSetting string `json:"serviceVersionSetting,omitempty"`
 	// set labels/annotation name to get pod's dependencies
 	DependsSetting string `json:"dependsSetting,omitempty"`
 	// health service pod replicas, default is 1
 	Replicas int32 `json:"replicas,omitempty"`
-	// health service deployment ServiceAccountName, default is default
+	// health service deployment ServiceAccountName, default is ibm-healthcheck-operator-cluster
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
 	// health srevice deployment node selector, default is empty
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
-	// health srevice deployment tolerations, default is empty
+	// health srevice deployment tolerations, added to the default dedicated and CriticalAddonsOnly tolerations.
+	// A toleration with the same key and effect as a default one replaces it
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
-	// memcached deployment security context, default is empty
+	// health service container security context, every field set here overrides the default
+	// restricted security context of the container
 	SecurityContext corev1.SecurityContext `json:"securityContext,omitempty"`
 	// health srevice deployment hostnetwork, default is false
 	HostNetwork bool `json:"hostNetwork,omitempty"`
@@ -96,6 +102,10 @@ type HealthServiceSpec struct {
 	Memcached HealthServiceSpecMemcached `json:"memcached,omitempty"`
 	// HealthService defines the desired state of HealthService.HealthService
 	HealthService HealthServiceSpecHealthService `json:"healthService,omitempty"`
+	// Ingress defines the Ingress of the health service
+	Ingress Ingress `json:"ingress,omitempty"`
+	// Exposure defines how the health service is exposed outside of the cluster
+	Exposure Exposure `json:"exposure,omitempty"`
 }
 
 // HealthServiceStatus defines the observed state of HealthService
@@ -104,11 +114,29 @@ type HealthServiceStatus struct {
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
 
+	// MemcachedNodes are the names of the memcached pods.
+	// Deprecated: use MemcachedPods.
 	// +listType=set
-	// MemcachedNodes are the names of the memcached pods
 	MemcachedNodes []string `json:"memcachedNodes,omitempty"`
-	// HealthCheckNodes are the names of the Healch Service pods
+	// MemcachedPods are the states of the memcached pods
+	MemcachedPods []PodStatus `json:"memcachedPods,omitempty"`
+	// HealthCheckNodes are the names of the Health Service pods.
+	// Deprecated: use HealthCheckPods.
+	// +listType=set
 	HealthCheckNodes []string `json:"healthCheckNodes,omitempty"`
+	// HealthCheckPods are the states of the Health Service pods
+	HealthCheckPods []PodStatus `json:"healthCheckPods,omitempty"`
+	// MemcachedImage is the image of the memcached pods, pinned to the digest they run once they all run the same one
+	MemcachedImage string `json:"memcachedImage,omitempty"`
+	// HealthCheckImage is the image of the Health Service pods, pinned to the digest they run once they all
+	// run the same one
+	HealthCheckImage string `json:"healthCheckImage,omitempty"`
+	// Phase is a summary of the HealthService conditions
+	Phase Phase `json:"phase,omitempty"`
+	// Conditions are the Ready, Progressing, Degraded and ConfigValid observations of the HealthService
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -116,6 +144,9 @@ type HealthServiceStatus struct {
 // HealthService is the Schema for the healthservices API
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=healthservices,scope=Namespaced
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type HealthService struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -28,14 +28,51 @@ type MustGatherConfigSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
+
+	// raw gather config, shell assignments such as modules="overview,failure".
+	// The typed fields below override the same settings.
 	GatherConfig string `json:"gatherConfig,omitempty"`
+	// modules to gather, among overview, system, failure, ocp and cloudpak
+	Modules []string `json:"modules,omitempty"`
+	// namespaces to gather
+	Namespaces []string `json:"namespaces,omitempty"`
+	// selector of the labels of the resources to gather
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	// sensitive data removed from the gathered data once the gather succeeds, default is none
+	Redaction *Redaction `json:"redaction,omitempty"`
+	// name of the MustGatherService the configmap is labeled for,
+	// default is the MustGatherService of the namespace when there is only one
+	MustGatherServiceName string `json:"mustGatherServiceName,omitempty"`
+}
+
+// Redaction defines the sensitive data removed from the gathered data before it is downloaded or
+// uploaded. The counts of the redacted data are in the status of the MustGatherJob.
+type Redaction struct {
+	// regular expressions, in RE2 syntax, of the text replaced in all the gathered files,
+	// such as customer host names
+	Rules []string `json:"rules,omitempty"`
+	// regular expressions, in RE2 syntax, of the key names whose values are replaced in the
+	// gathered resources, such as (?i)password|token
+	KeyPatterns []string `json:"keyPatterns,omitempty"`
+	// kinds of the resources removed from the gathered data, such as Secret
+	Kinds []string `json:"kinds,omitempty"`
+	// text the redacted data is replaced with, default is REDACTED
+	Replacement string `json:"replacement,omitempty"`
 }
 
+// ConditionValid indicates that the MustGatherConfig is valid and rendered into its configmap
+const ConditionValid = "Valid"
+
 // MustGatherConfigStatus defines the observed state of MustGatherConfig
 type MustGatherConfigStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
+
+	// Conditions are the Valid observations of the MustGatherConfig
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -43,6 +80,8 @@ type MustGatherConfigStatus struct {
 // MustGatherConfig is the Schema for the mustgatherconfigs API
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=mustgatherconfigs,scope=Namespaced
+// +kubebuilder:printcolumn:name="Valid",type=string,JSONPath=`.status.conditions[?(@.type=="Valid")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type MustGatherConfig struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
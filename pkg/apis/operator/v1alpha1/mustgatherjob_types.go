@@ -17,6 +17,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -28,14 +29,147 @@ type MustGatherJobSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
-	// must gather image
+	// must gather image, it overrides the MUST_GATHER_IMAGE of the operator
 	Image Image `json:"image,omitempty"`
 	// must gather job ServiceAccountName, default is default
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
-	// must gather config name, default is default
+	// name of the must gather config mounted at /usr/bin/gather_config, default is none
 	MustGatherConfigName string `json:"mustgatherConfigName,omitempty"`
-	// must gather command, default is gather
+	// name of the MustGatherService whose volume the data is gathered to,
+	// default is the MustGatherService of the namespace when there is only one
+	MustGatherServiceName string `json:"mustGatherServiceName,omitempty"`
+	// must gather command, split into words as a shell does, default is gather.
+	// Deprecated: use command and args.
 	MustGatherCommand string `json:"mustgatherCommand,omitempty"`
+	// must gather command, it overrides mustgatherCommand, default is gather
+	Command []string `json:"command,omitempty"`
+	// arguments of the must gather command
+	Args []string `json:"args,omitempty"`
+	// run id, changing it runs the gather again, as any other change of the spec.
+	// The jobs of the previous run are then deleted, its gathered data is kept.
+	RunID string `json:"runID,omitempty"`
+	// cancel the gather, the running job is deleted, default is false.
+	// The gather runs again when cancel is reset.
+	Cancel bool `json:"cancel,omitempty"`
+	// must gather job resources, default is none
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// must gather job node selector, default is empty
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// must gather job tolerations, added to the tolerations of the must gather service
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// must gather job security context, default is empty
+	SecurityContext corev1.SecurityContext `json:"securityContext,omitempty"`
+	// secrets to pull the must gather image, default is none
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// sign the manifest of the gathered files once the gather succeeds, default is no manifest
+	Integrity *Integrity `json:"integrity,omitempty"`
+	// upload the gathered data to an object storage once the gather succeeds,
+	// default is the upload of its MustGatherService
+	Upload *Upload `json:"upload,omitempty"`
+}
+
+// Integrity defines the key the manifest of the gathered files is signed with. The gathered data is
+// archived in <job name>.tar.gz, unless it is an archive already, and the manifest of the SHA-256
+// digests of its files is written next to it.
+type Integrity struct {
+	// name of the secret which holds the PEM encoded ed25519 privateKey
+	SigningKeySecretName string `json:"signingKeySecretName"`
+}
+
+// JobPhase is the lifecycle phase of a must gather job
+type JobPhase string
+
+const (
+	// JobPending means the job is created and its pod is not running yet
+	JobPending JobPhase = "Pending"
+	// JobRunning means the gather pod is running
+	JobRunning JobPhase = "Running"
+	// JobSucceeded means the gather completed and its data is on the must gather PVC
+	JobSucceeded JobPhase = "Succeeded"
+	// JobFailed means the gather failed after all its retries
+	JobFailed JobPhase = "Failed"
+	// JobCancelled means the gather was cancelled before it finished
+	JobCancelled JobPhase = "Cancelled"
+)
+
+const (
+	// ConditionComplete indicates that the must gather job completed
+	ConditionComplete = "Complete"
+	// ConditionFailed indicates that the must gather job failed
+	ConditionFailed = "Failed"
+	// ConditionRedacted indicates that the redaction policy of the must gather config is applied to the gathered data
+	ConditionRedacted = "Redacted"
+	// ConditionSigned indicates that the manifest of the gathered data is written and signed
+	ConditionSigned = "Signed"
+	// ConditionUploaded indicates that the gathered data is uploaded to the object storage
+	ConditionUploaded = "Uploaded"
+)
+
+// RedactionStatus is the observed state of the redaction of the gathered data
+type RedactionStatus struct {
+	// Phase is one of Pending, Running, Succeeded or Failed
+	Phase JobPhase `json:"phase,omitempty"`
+	// JobName is the name of the batch Job which redacts the data
+	JobName string `json:"jobName,omitempty"`
+	// FilesScanned is the number of gathered text files
+	FilesScanned int32 `json:"filesScanned,omitempty"`
+	// FilesSkipped is the number of gathered files which can't be redacted: the binary files, such as
+	// nested archives, and the YAML or JSON files which can't be parsed while the policy sets key
+	// patterns or kinds. The data is not signed nor uploaded when files are skipped.
+	FilesSkipped int32 `json:"filesSkipped,omitempty"`
+	// FilesRedacted is the number of files changed or removed by the redaction
+	FilesRedacted int32 `json:"filesRedacted,omitempty"`
+	// RuleMatches is the number of texts replaced by the rules
+	RuleMatches int32 `json:"ruleMatches,omitempty"`
+	// KeysRedacted is the number of values replaced because of their key name
+	KeysRedacted int32 `json:"keysRedacted,omitempty"`
+	// ResourcesRemoved is the number of resources removed because of their kind
+	ResourcesRemoved int32 `json:"resourcesRemoved,omitempty"`
+	// CompletionTime is when the redaction succeeded or failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// IntegrityStatus is the observed state of the signed manifest of the gathered data
+type IntegrityStatus struct {
+	// Phase is one of Pending, Running, Succeeded or Failed
+	Phase JobPhase `json:"phase,omitempty"`
+	// JobName is the name of the batch Job which signs the manifest
+	JobName string `json:"jobName,omitempty"`
+	// Archive is the name of the archive of the gathered data on the must gather PVC
+	Archive string `json:"archive,omitempty"`
+	// Manifest is the name of the manifest of the archive files, next to the archive
+	Manifest string `json:"manifest,omitempty"`
+	// Signature is the name of the base64 encoded ed25519 signature of the manifest, next to the archive
+	Signature string `json:"signature,omitempty"`
+	// Digest is the digest of the archive, as sha256:<hex>
+	Digest string `json:"digest,omitempty"`
+	// Files is the number of files in the manifest
+	Files int32 `json:"files,omitempty"`
+	// Signed is whether the manifest is signed
+	Signed bool `json:"signed,omitempty"`
+	// KeyFingerprint identifies the public key the signature is verified with, as sha256:<hex>
+	KeyFingerprint string `json:"keyFingerprint,omitempty"`
+	// CompletionTime is when the signing succeeded or failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// UploadStatus is the observed state of the upload of the gathered data
+type UploadStatus struct {
+	// Phase is one of Pending, Running, Succeeded or Failed
+	Phase JobPhase `json:"phase,omitempty"`
+	// JobName is the name of the batch Job which uploads the data
+	JobName string `json:"jobName,omitempty"`
+	// URL is the URL of the uploaded object
+	URL string `json:"url,omitempty"`
+	// Checksum is the digest of the uploaded object, as sha256:<hex>, the digest of the signed archive
+	// when the manifest is signed
+	Checksum string `json:"checksum,omitempty"`
+	// ManifestURL is the URL of the uploaded manifest of the signed archive
+	ManifestURL string `json:"manifestURL,omitempty"`
+	// SignatureURL is the URL of the uploaded signature of the manifest
+	SignatureURL string `json:"signatureURL,omitempty"`
+	// CompletionTime is when the upload succeeded or failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
 }
 
 // MustGatherJobStatus defines the observed state of MustGatherJob
@@ -43,6 +177,36 @@ type MustGatherJobStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
+
+	// Phase is one of Pending, Running, Succeeded, Failed or Cancelled
+	Phase JobPhase `json:"phase,omitempty"`
+	// JobName is the name of the batch Job of the current run
+	JobName string `json:"jobName,omitempty"`
+	// ObservedGeneration is the generation of the MustGatherJob the current run or cancellation is for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// PodName is the name of the latest pod of the job
+	PodName string `json:"podName,omitempty"`
+	// Image is the must gather image the job of the current run is created with
+	Image string `json:"image,omitempty"`
+	// StartTime is when the job started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is when the job succeeded, failed or was cancelled
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// FailureReason is the reason of the job failure, such as BackoffLimitExceeded
+	FailureReason string `json:"failureReason,omitempty"`
+	// ArtifactPath is the directory of the gathered data on the must gather PVC
+	ArtifactPath string `json:"artifactPath,omitempty"`
+	// Redaction is the state of the redaction of the gathered data, when the must gather config
+	// has a redaction policy
+	Redaction *RedactionStatus `json:"redaction,omitempty"`
+	// Integrity is the state of the signed manifest of the gathered data, when integrity is set
+	Integrity *IntegrityStatus `json:"integrity,omitempty"`
+	// Upload is the state of the upload of the gathered data, when an upload is set
+	Upload *UploadStatus `json:"upload,omitempty"`
+	// Conditions are the Complete, Failed, Redacted, Signed and Uploaded observations of the MustGatherJob
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -50,6 +214,11 @@ type MustGatherJobStatus struct {
 // MustGatherJob is the Schema for the mustgatherjobs API
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=mustgatherjobs,scope=Namespaced
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Pod",type=string,JSONPath=`.status.podName`
+// +kubebuilder:printcolumn:name="Started",type=date,JSONPath=`.status.startTime`
+// +kubebuilder:printcolumn:name="Completed",type=date,JSONPath=`.status.completionTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type MustGatherJob struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -0,0 +1,109 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConcurrencyPolicy is how a scheduled run starts while the previous runs are not finished
+type ConcurrencyPolicy string
+
+const (
+	// AllowConcurrent starts the run alongside the previous runs
+	AllowConcurrent ConcurrencyPolicy = "Allow"
+	// ForbidConcurrent skips the run while a previous run is not finished
+	ForbidConcurrent ConcurrencyPolicy = "Forbid"
+	// ReplaceConcurrent deletes the previous runs which are not finished, and starts the run
+	ReplaceConcurrent ConcurrencyPolicy = "Replace"
+)
+
+// ConditionScheduleValid indicates that the cron expression and the time zone of the MustGatherSchedule are valid
+const ConditionScheduleValid = "ScheduleValid"
+
+// MustGatherScheduleSpec defines the desired state of MustGatherSchedule
+type MustGatherScheduleSpec struct {
+	// cron expression of the runs, five fields minute, hour, day of month, month and day of week,
+	// or one of @yearly, @monthly, @weekly, @daily and @hourly
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+	// IANA time zone of the schedule such as America/New_York, default is UTC
+	TimeZone string `json:"timeZone,omitempty"`
+	// how a run starts while the previous runs are not finished, one of Allow, Forbid or Replace, default is Forbid
+	// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+	// suspend the next runs, the started runs are not affected, default is false
+	Suspend bool `json:"suspend,omitempty"`
+	// deadline in seconds to start a run which missed its scheduled time, default is no deadline
+	// +kubebuilder:validation:Minimum=0
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+	// number of succeeded MustGatherJobs to keep, default is 3
+	// +kubebuilder:validation:Minimum=0
+	SuccessfulJobsHistoryLimit *int32 `json:"successfulJobsHistoryLimit,omitempty"`
+	// number of failed or cancelled MustGatherJobs to keep, default is 1
+	// +kubebuilder:validation:Minimum=0
+	FailedJobsHistoryLimit *int32 `json:"failedJobsHistoryLimit,omitempty"`
+	// spec of the MustGatherJob created for each run
+	JobTemplate MustGatherJobSpec `json:"jobTemplate,omitempty"`
+}
+
+// MustGatherScheduleStatus defines the observed state of MustGatherSchedule
+type MustGatherScheduleStatus struct {
+	// Active are the names of the MustGatherJobs which are not finished
+	Active []string `json:"active,omitempty"`
+	// LastScheduleTime is the scheduled time of the last run
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// LastSuccessfulTime is the completion time of the last succeeded run
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+	// NextScheduleTime is the scheduled time of the next run
+	NextScheduleTime *metav1.Time `json:"nextScheduleTime,omitempty"`
+	// Conditions are the ScheduleValid observations of the MustGatherSchedule
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MustGatherSchedule is the Schema for the mustgatherschedules API
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=mustgatherschedules,scope=Namespaced
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+// +kubebuilder:printcolumn:name="Suspend",type=boolean,JSONPath=`.spec.suspend`
+// +kubebuilder:printcolumn:name="Last Schedule",type=date,JSONPath=`.status.lastScheduleTime`
+// +kubebuilder:printcolumn:name="Next Schedule",type=date,JSONPath=`.status.nextScheduleTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type MustGatherSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MustGatherScheduleSpec   `json:"spec,omitempty"`
+	Status MustGatherScheduleStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MustGatherScheduleList contains a list of MustGatherSchedule
+type MustGatherScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MustGatherSchedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MustGatherSchedule{}, &MustGatherScheduleList{})
+}
@@ -17,27 +17,90 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+// StorageClassSelection is how the storage class of the pvc is selected when it is not set
+type StorageClassSelection string
+
+const (
+	// StorageClassPreferDefault selects the default storage class, else the first other class
+	StorageClassPreferDefault StorageClassSelection = "PreferDefault"
+	// StorageClassPreferReadWriteMany selects a class whose provisioner supports ReadWriteMany volumes,
+	// the default class first, the pvc is then ReadWriteMany. It falls back to PreferDefault.
+	StorageClassPreferReadWriteMany StorageClassSelection = "PreferReadWriteMany"
+)
+
 // PersistentVolumeClaim defines the desired persistent volume claim
 type PersistentVolumeClaim struct {
 	// MustGatherService pvc name
 	Name string `json:"name"`
-	// resources defines the request storage size
+	// resources defines the request storage size, a larger request expands the pvc
+	// when its storage class allows volume expansion
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
-	// storageClassName defines the storageclass name, default is default storageclass in cluster
+	// storageClassName defines the storageclass name, default is selected by storageClassSelection
+	StorageClassName string `json:"storageClassName,omitempty"`
+	// storageClassSelection is one of PreferDefault or PreferReadWriteMany, default is PreferDefault
+	// +kubebuilder:validation:Enum=PreferDefault;PreferReadWriteMany
+	StorageClassSelection StorageClassSelection `json:"storageClassSelection,omitempty"`
+	// provisioners of the storage classes which can be selected, default is any provisioner
+	AllowedProvisioners []string `json:"allowedProvisioners,omitempty"`
+	// accessMode of the pvc, ReadWriteMany also prefers the classes whose provisioner supports it.
+	// Default is ReadWriteMany when PreferReadWriteMany finds such a class, else ReadWriteOnce.
+	// +kubebuilder:validation:Enum=ReadWriteOnce;ReadWriteMany
+	AccessMode corev1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+}
+
+const (
+	// ConditionPVCCreated indicates that the pvc is created, it is not when no storage class matches
+	ConditionPVCCreated = "PVCCreated"
+	// ConditionResizing indicates that the pvc is being expanded
+	ConditionResizing = "Resizing"
+	// ConditionFileSystemResizePending indicates that the volume is expanded and its file system
+	// is resized when the must gather service pod restarts
+	ConditionFileSystemResizePending = "FileSystemResizePending"
+	// ConditionPruned indicates that the last prune of the archives succeeded
+	ConditionPruned = "Pruned"
+	// ConditionExposed indicates that the Ingress, Route or HTTPRoute of the must gather service is created
+	ConditionExposed = "Exposed"
+)
+
+// PersistentVolumeClaimStatus defines the observed state of the persistent volume claim
+type PersistentVolumeClaimStatus struct {
+	// StorageClassName is the storage class of the pvc
 	StorageClassName string `json:"storageClassName,omitempty"`
+	// AccessModes are the access modes of the volume
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+	// Requested is the storage requested by the pvc
+	Requested *resource.Quantity `json:"requested,omitempty"`
+	// Capacity is the storage capacity of the volume
+	Capacity *resource.Quantity `json:"capacity,omitempty"`
+}
+
+// RetentionStatus defines the observed state of the archives on the must gather pvc, as measured
+// by the last prune
+type RetentionStatus struct {
+	// UsedStorage is the total size of the archives
+	UsedStorage *resource.Quantity `json:"usedStorage,omitempty"`
+	// ArchiveCount is the number of archives
+	ArchiveCount int32 `json:"archiveCount,omitempty"`
+	// PrunedCount is the number of archives deleted by the last prune
+	PrunedCount int32 `json:"prunedCount,omitempty"`
+	// LastPruneTime is when the last prune completed
+	LastPruneTime *metav1.Time `json:"lastPruneTime,omitempty"`
+	// NextPruneTime is when the next prune starts
+	NextPruneTime *metav1.Time `json:"nextPruneTime,omitempty"`
 }
 
 // MustGather defines the desired MustGather service
 type MustGather struct {
-	// MustGatherService deployment name
-	Name string `json:"name"`
-	// deprecated, define image in operator.yaml
+	// MustGatherService statefulset, service and ingress name, default is the MustGatherService name
+	Name string `json:"name,omitempty"`
+	// MustGatherService image, it overrides the MUST_GATHER_SERVICE_IMAGE of the operator
 	Image Image `json:"image,omitempty"`
 	// MustGatherService deployment ServiceAccountName, default is default
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
@@ -57,6 +120,20 @@ type MustGather struct {
 	HostNetwork bool `json:"hostNetwork,omitempty"`
 }
 
+// Retention defines which gathered archives are kept on the must gather pvc, the oldest archives
+// are pruned first
+type Retention struct {
+	// maximum age of an archive, such as 168h, default is no limit
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+	// maximum number of archives, default is no limit
+	// +kubebuilder:validation:Minimum=0
+	MaxCount *int32 `json:"maxCount,omitempty"`
+	// maximum total size of the archives, such as 8Gi, default is no limit
+	MaxTotalSize *resource.Quantity `json:"maxTotalSize,omitempty"`
+	// interval between two prunes, default is 1h
+	PruneInterval *metav1.Duration `json:"pruneInterval,omitempty"`
+}
+
 // MustGatherServiceSpec defines the desired state of MustGatherService
 type MustGatherServiceSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -65,6 +142,15 @@ type MustGatherServiceSpec struct {
 	MustGather MustGather `json:"mustGather,omitempty"`
 	// persistentVolumeClaim defines the desired persistent volume claim
 	PersistentVolumeClaim PersistentVolumeClaim `json:"persistentVolumeClaim,omitempty"`
+	// Ingress defines the Ingress of the must gather service
+	Ingress Ingress `json:"ingress,omitempty"`
+	// Exposure defines how the must gather service is exposed outside of the cluster
+	Exposure Exposure `json:"exposure,omitempty"`
+	// Retention defines which archives are kept on the must gather pvc, default is all the archives
+	Retention Retention `json:"retention,omitempty"`
+	// Upload is the object storage the MustGatherJobs of the namespace upload their data to,
+	// unless they set their own, default is no upload
+	Upload *Upload `json:"upload,omitempty"`
 }
 
 // MustGatherServiceStatus defines the observed state of MustGatherService
@@ -72,8 +158,24 @@ type MustGatherServiceStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
-	// MustGatherServiceNodes are the names of the MustGatherService pods
+
+	// MustGatherServiceNodes are the names of the MustGatherService pods.
+	// Deprecated: use MustGatherServicePods.
+	// +listType=set
 	MustGatherServiceNodes []string `json:"mustGatherServiceNodes,omitempty"`
+	// MustGatherServicePods are the states of the MustGatherService pods
+	MustGatherServicePods []PodStatus `json:"mustGatherServicePods,omitempty"`
+	// MustGatherServiceImage is the image resolved for the MustGatherService pods
+	MustGatherServiceImage string `json:"mustGatherServiceImage,omitempty"`
+	// PersistentVolumeClaim is the state of the must gather pvc
+	PersistentVolumeClaim PersistentVolumeClaimStatus `json:"persistentVolumeClaim,omitempty"`
+	// Retention is the state of the archives on the must gather pvc
+	Retention RetentionStatus `json:"retention,omitempty"`
+	// Conditions are the PVCCreated, Resizing and FileSystemResizePending observations of the pvc, the
+	// Pruned observation of the archives, and the Exposed observation of the service
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -21,9 +21,54 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Exposure) DeepCopyInto(out *Exposure) {
+	*out = *in
+	out.Route = in.Route
+	in.HTTPRoute.DeepCopyInto(&out.HTTPRoute)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Exposure.
+func (in *Exposure) DeepCopy() *Exposure {
+	if in == nil {
+		return nil
+	}
+	out := new(Exposure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPRouteExposure) DeepCopyInto(out *HTTPRouteExposure) {
+	*out = *in
+	if in.ParentRefs != nil {
+		in, out := &in.ParentRefs, &out.ParentRefs
+		*out = make([]ParentReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Hostnames != nil {
+		in, out := &in.Hostnames, &out.Hostnames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPRouteExposure.
+func (in *HTTPRouteExposure) DeepCopy() *HTTPRouteExposure {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPRouteExposure)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HealthService) DeepCopyInto(out *HealthService) {
 	*out = *in
@@ -90,6 +135,8 @@ func (in *HealthServiceSpec) DeepCopyInto(out *HealthServiceSpec) {
 	*out = *in
 	in.Memcached.DeepCopyInto(&out.Memcached)
 	in.HealthService.DeepCopyInto(&out.HealthService)
+	in.Ingress.DeepCopyInto(&out.Ingress)
+	in.Exposure.DeepCopyInto(&out.Exposure)
 	return
 }
 
@@ -182,11 +229,28 @@ func (in *HealthServiceStatus) DeepCopyInto(out *HealthServiceStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.MemcachedPods != nil {
+		in, out := &in.MemcachedPods, &out.MemcachedPods
+		*out = make([]PodStatus, len(*in))
+		copy(*out, *in)
+	}
 	if in.HealthCheckNodes != nil {
 		in, out := &in.HealthCheckNodes, &out.HealthCheckNodes
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.HealthCheckPods != nil {
+		in, out := &in.HealthCheckPods, &out.HealthCheckPods
+		*out = make([]PodStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -216,6 +280,70 @@ func (in *Image) DeepCopy() *Image {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Ingress) DeepCopyInto(out *Ingress) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Ingress.
+func (in *Ingress) DeepCopy() *Ingress {
+	if in == nil {
+		return nil
+	}
+	out := new(Ingress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Integrity) DeepCopyInto(out *Integrity) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Integrity.
+func (in *Integrity) DeepCopy() *Integrity {
+	if in == nil {
+		return nil
+	}
+	out := new(Integrity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IntegrityStatus) DeepCopyInto(out *IntegrityStatus) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IntegrityStatus.
+func (in *IntegrityStatus) DeepCopy() *IntegrityStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IntegrityStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MustGather) DeepCopyInto(out *MustGather) {
 	*out = *in
@@ -259,8 +387,8 @@ func (in *MustGatherConfig) DeepCopyInto(out *MustGatherConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -318,6 +446,26 @@ func (in *MustGatherConfigList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MustGatherConfigSpec) DeepCopyInto(out *MustGatherConfigSpec) {
 	*out = *in
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Redaction != nil {
+		in, out := &in.Redaction, &out.Redaction
+		*out = new(Redaction)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -334,6 +482,13 @@ func (in *MustGatherConfigSpec) DeepCopy() *MustGatherConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MustGatherConfigStatus) DeepCopyInto(out *MustGatherConfigStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -352,8 +507,8 @@ func (in *MustGatherJob) DeepCopyInto(out *MustGatherJob) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -412,6 +567,47 @@ func (in *MustGatherJobList) DeepCopyObject() runtime.Object {
 func (in *MustGatherJobSpec) DeepCopyInto(out *MustGatherJobSpec) {
 	*out = *in
 	out.Image = in.Image
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.SecurityContext.DeepCopyInto(&out.SecurityContext)
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Integrity != nil {
+		in, out := &in.Integrity, &out.Integrity
+		*out = new(Integrity)
+		**out = **in
+	}
+	if in.Upload != nil {
+		in, out := &in.Upload, &out.Upload
+		*out = new(Upload)
+		**out = **in
+	}
 	return
 }
 
@@ -428,6 +624,36 @@ func (in *MustGatherJobSpec) DeepCopy() *MustGatherJobSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MustGatherJobStatus) DeepCopyInto(out *MustGatherJobStatus) {
 	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Redaction != nil {
+		in, out := &in.Redaction, &out.Redaction
+		*out = new(RedactionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Integrity != nil {
+		in, out := &in.Integrity, &out.Integrity
+		*out = new(IntegrityStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Upload != nil {
+		in, out := &in.Upload, &out.Upload
+		*out = new(UploadStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -441,6 +667,139 @@ func (in *MustGatherJobStatus) DeepCopy() *MustGatherJobStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MustGatherSchedule) DeepCopyInto(out *MustGatherSchedule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MustGatherSchedule.
+func (in *MustGatherSchedule) DeepCopy() *MustGatherSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(MustGatherSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MustGatherSchedule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MustGatherScheduleList) DeepCopyInto(out *MustGatherScheduleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MustGatherSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MustGatherScheduleList.
+func (in *MustGatherScheduleList) DeepCopy() *MustGatherScheduleList {
+	if in == nil {
+		return nil
+	}
+	out := new(MustGatherScheduleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MustGatherScheduleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MustGatherScheduleSpec) DeepCopyInto(out *MustGatherScheduleSpec) {
+	*out = *in
+	if in.StartingDeadlineSeconds != nil {
+		in, out := &in.StartingDeadlineSeconds, &out.StartingDeadlineSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.SuccessfulJobsHistoryLimit != nil {
+		in, out := &in.SuccessfulJobsHistoryLimit, &out.SuccessfulJobsHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.FailedJobsHistoryLimit != nil {
+		in, out := &in.FailedJobsHistoryLimit, &out.FailedJobsHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	in.JobTemplate.DeepCopyInto(&out.JobTemplate)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MustGatherScheduleSpec.
+func (in *MustGatherScheduleSpec) DeepCopy() *MustGatherScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MustGatherScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MustGatherScheduleStatus) DeepCopyInto(out *MustGatherScheduleStatus) {
+	*out = *in
+	if in.Active != nil {
+		in, out := &in.Active, &out.Active
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSuccessfulTime != nil {
+		in, out := &in.LastSuccessfulTime, &out.LastSuccessfulTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextScheduleTime != nil {
+		in, out := &in.NextScheduleTime, &out.NextScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MustGatherScheduleStatus.
+func (in *MustGatherScheduleStatus) DeepCopy() *MustGatherScheduleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MustGatherScheduleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MustGatherService) DeepCopyInto(out *MustGatherService) {
 	*out = *in
@@ -507,6 +866,14 @@ func (in *MustGatherServiceSpec) DeepCopyInto(out *MustGatherServiceSpec) {
 	*out = *in
 	in.MustGather.DeepCopyInto(&out.MustGather)
 	in.PersistentVolumeClaim.DeepCopyInto(&out.PersistentVolumeClaim)
+	in.Ingress.DeepCopyInto(&out.Ingress)
+	in.Exposure.DeepCopyInto(&out.Exposure)
+	in.Retention.DeepCopyInto(&out.Retention)
+	if in.Upload != nil {
+		in, out := &in.Upload, &out.Upload
+		*out = new(Upload)
+		**out = **in
+	}
 	return
 }
 
@@ -528,6 +895,20 @@ func (in *MustGatherServiceStatus) DeepCopyInto(out *MustGatherServiceStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.MustGatherServicePods != nil {
+		in, out := &in.MustGatherServicePods, &out.MustGatherServicePods
+		*out = make([]PodStatus, len(*in))
+		copy(*out, *in)
+	}
+	in.PersistentVolumeClaim.DeepCopyInto(&out.PersistentVolumeClaim)
+	in.Retention.DeepCopyInto(&out.Retention)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -541,10 +922,31 @@ func (in *MustGatherServiceStatus) DeepCopy() *MustGatherServiceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ParentReference) DeepCopyInto(out *ParentReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParentReference.
+func (in *ParentReference) DeepCopy() *ParentReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ParentReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PersistentVolumeClaim) DeepCopyInto(out *PersistentVolumeClaim) {
 	*out = *in
 	in.Resources.DeepCopyInto(&out.Resources)
+	if in.AllowedProvisioners != nil {
+		in, out := &in.AllowedProvisioners, &out.AllowedProvisioners
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -558,6 +960,104 @@ func (in *PersistentVolumeClaim) DeepCopy() *PersistentVolumeClaim {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PersistentVolumeClaimStatus) DeepCopyInto(out *PersistentVolumeClaimStatus) {
+	*out = *in
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]v1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
+	if in.Requested != nil {
+		in, out := &in.Requested, &out.Requested
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PersistentVolumeClaimStatus.
+func (in *PersistentVolumeClaimStatus) DeepCopy() *PersistentVolumeClaimStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PersistentVolumeClaimStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodStatus) DeepCopyInto(out *PodStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodStatus.
+func (in *PodStatus) DeepCopy() *PodStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PodStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Redaction) DeepCopyInto(out *Redaction) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.KeyPatterns != nil {
+		in, out := &in.KeyPatterns, &out.KeyPatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Kinds != nil {
+		in, out := &in.Kinds, &out.Kinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Redaction.
+func (in *Redaction) DeepCopy() *Redaction {
+	if in == nil {
+		return nil
+	}
+	out := new(Redaction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedactionStatus) DeepCopyInto(out *RedactionStatus) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedactionStatus.
+func (in *RedactionStatus) DeepCopy() *RedactionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RedactionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Resource) DeepCopyInto(out *Resource) {
 	*out = *in
@@ -591,3 +1091,137 @@ func (in *Resources) DeepCopy() *Resources {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Retention) DeepCopyInto(out *Retention) {
+	*out = *in
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxCount != nil {
+		in, out := &in.MaxCount, &out.MaxCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxTotalSize != nil {
+		in, out := &in.MaxTotalSize, &out.MaxTotalSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.PruneInterval != nil {
+		in, out := &in.PruneInterval, &out.PruneInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Retention.
+func (in *Retention) DeepCopy() *Retention {
+	if in == nil {
+		return nil
+	}
+	out := new(Retention)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetentionStatus) DeepCopyInto(out *RetentionStatus) {
+	*out = *in
+	if in.UsedStorage != nil {
+		in, out := &in.UsedStorage, &out.UsedStorage
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.LastPruneTime != nil {
+		in, out := &in.LastPruneTime, &out.LastPruneTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextPruneTime != nil {
+		in, out := &in.NextPruneTime, &out.NextPruneTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetentionStatus.
+func (in *RetentionStatus) DeepCopy() *RetentionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RetentionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteExposure) DeepCopyInto(out *RouteExposure) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteExposure.
+func (in *RouteExposure) DeepCopy() *RouteExposure {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteExposure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Upload) DeepCopyInto(out *Upload) {
+	*out = *in
+	out.TLS = in.TLS
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Upload.
+func (in *Upload) DeepCopy() *Upload {
+	if in == nil {
+		return nil
+	}
+	out := new(Upload)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UploadStatus) DeepCopyInto(out *UploadStatus) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UploadStatus.
+func (in *UploadStatus) DeepCopy() *UploadStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UploadStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UploadTLS) DeepCopyInto(out *UploadTLS) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UploadTLS.
+func (in *UploadTLS) DeepCopy() *UploadTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(UploadTLS)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,61 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package artifact names the gathered data of a must gather job on the must gather volume. The gather
+// of the job writes a directory or a file named after the job, or an archive of it.
+package artifact
+
+import (
+	"io/ioutil"
+)
+
+// Extensions are the extensions of the archives of the gathered data
+var Extensions = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// Names returns the names the data gathered by the job may have. The runs of a MustGatherJob are named
+// <name>-<generation>, so that a prefix of the job name would match the data of other runs.
+func Names(job string) []string {
+	names := []string{job}
+	for _, ext := range Extensions {
+		names = append(names, job+ext)
+	}
+	return names
+}
+
+// Match returns whether name is the data gathered by the job
+func Match(name, job string) bool {
+	for _, n := range Names(job) {
+		if name == n {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the names of the entries of dir gathered by the job
+func Find(dir, job string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if Match(entry.Name(), job) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
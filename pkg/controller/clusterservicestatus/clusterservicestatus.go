@@ -0,0 +1,278 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package clusterservicestatus
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	clusterhealthv1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/clusterhealth/v1"
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "ibm-healthcheck-operator"
+
+	healthServiceNamespaceLabel = "clusterhealth.ibm.com/healthservice-namespace"
+	// healthServiceNameLabel is the HealthService name as a label value, see healthServiceLabelValue
+	healthServiceNameLabel = "clusterhealth.ibm.com/healthservice-name"
+	// healthServiceNameAnnotation is the full HealthService name
+	healthServiceNameAnnotation = "clusterhealth.ibm.com/healthservice-name"
+
+	// defaultServiceVersionSetting is where the service version is read when the HealthService doesn't set it
+	defaultServiceVersionSetting = "Annotations:productVersion"
+)
+
+// waiting reasons of a container which mean the pod won't start without an intervention
+var failedWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// serviceStatus accumulates the state of the pods of one service
+type serviceStatus struct {
+	name         string
+	version      string
+	pending      bool
+	restartCount int32
+	dependencies map[string]bool
+	podFailures  map[string]string
+}
+
+// podSetting is a parsed ServiceNameSetting, ServiceVersionSetting or DependsSetting, such as "Annotations:productName"
+type podSetting struct {
+	fromLabels bool
+	key        string
+}
+
+// parsePodSetting parses a "Labels:<name>" or "Annotations:<name>" setting, returns false if the setting is not valid
+func parsePodSetting(setting string) (podSetting, bool) {
+	parts := strings.SplitN(strings.TrimSpace(setting), ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return podSetting{}, false
+	}
+	switch strings.ToLower(parts[0]) {
+	case "labels":
+		return podSetting{fromLabels: true, key: parts[1]}, true
+	case "annotations":
+		return podSetting{key: parts[1]}, true
+	}
+	return podSetting{}, false
+}
+
+func (s podSetting) value(pod *corev1.Pod) string {
+	if s.fromLabels {
+		return pod.Labels[s.key]
+	}
+	return pod.Annotations[s.key]
+}
+
+// desiredClusterServiceStatuses returns a ClusterServiceStatus for every service found in the pods
+// through the ServiceNameSetting of the HealthService
+func desiredClusterServiceStatuses(h *operatorv1alpha1.HealthService, pods []corev1.Pod) []*clusterhealthv1.ClusterServiceStatus {
+	nameSetting, ok := parsePodSetting(h.Spec.HealthService.ServiceNameSetting)
+	if !ok {
+		return nil
+	}
+	dependsSetting, hasDepends := parsePodSetting(h.Spec.HealthService.DependsSetting)
+	version := h.Spec.HealthService.ServiceVersionSetting
+	if version == "" {
+		version = defaultServiceVersionSetting
+	}
+	versionSetting, hasVersion := parsePodSetting(version)
+
+	services := map[string]*serviceStatus{}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodSucceeded {
+			continue
+		}
+		name := nameSetting.value(pod)
+		if name == "" {
+			continue
+		}
+
+		svc, found := services[name]
+		if !found {
+			svc = &serviceStatus{
+				name:         name,
+				dependencies: map[string]bool{},
+				podFailures:  map[string]string{},
+			}
+			services[name] = svc
+		}
+		if svc.version == "" && hasVersion {
+			svc.version = versionSetting.value(pod)
+		}
+		if hasDepends {
+			for _, dep := range strings.Split(dependsSetting.value(pod), ",") {
+				if dep = strings.TrimSpace(dep); dep != "" {
+					svc.dependencies[dep] = true
+				}
+			}
+		}
+
+		for _, cs := range pod.Status.ContainerStatuses {
+			svc.restartCount += cs.RestartCount
+		}
+		if reason, failed := podFailureReason(pod); failed {
+			svc.podFailures[pod.Name] = reason
+		} else if !podReady(pod) {
+			svc.pending = true
+		}
+	}
+
+	names := []string{}
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := []*clusterhealthv1.ClusterServiceStatus{}
+	for _, name := range names {
+		statuses = append(statuses, newClusterServiceStatus(h, services[name]))
+	}
+	return statuses
+}
+
+func newClusterServiceStatus(h *operatorv1alpha1.HealthService, svc *serviceStatus) *clusterhealthv1.ClusterServiceStatus {
+	labels := map[string]string{
+		managedByLabel:                   managedByValue,
+		healthServiceNamespaceLabel:      h.Namespace,
+		healthServiceNameLabel:           healthServiceLabelValue(h.Name),
+		clusterhealthv1.ServiceNameLabel: labelValue(svc.name),
+	}
+	if svc.version != "" {
+		labels[clusterhealthv1.ServiceVersionLabel] = labelValue(svc.version)
+	}
+
+	state := clusterhealthv1.StateRunning
+	switch {
+	case len(svc.podFailures) > 0:
+		state = clusterhealthv1.StateFailed
+	case svc.pending:
+		state = clusterhealthv1.StatePending
+	}
+
+	var dependencies []string
+	for dep := range svc.dependencies {
+		dependencies = append(dependencies, dep)
+	}
+	sort.Strings(dependencies)
+
+	var podFailures map[string]string
+	if len(svc.podFailures) > 0 {
+		podFailures = svc.podFailures
+	}
+
+	return &clusterhealthv1.ClusterServiceStatus{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        clusterServiceStatusName(h.Namespace, svc.name),
+			Labels:      labels,
+			Annotations: map[string]string{healthServiceNameAnnotation: h.Name},
+		},
+		Status: clusterhealthv1.ClusterServiceStatusStatus{
+			CurrentState:       state,
+			RestartCount:       svc.restartCount,
+			StatusDependencies: dependencies,
+			PodFailureStatus:   podFailures,
+		},
+	}
+}
+
+// podFailureReason returns the reason why the pod is failing, false if the pod is not failing
+func podFailureReason(pod *corev1.Pod) (string, bool) {
+	if pod.Status.Phase == corev1.PodFailed {
+		if pod.Status.Reason != "" {
+			return pod.Status.Reason, true
+		}
+		return string(corev1.PodFailed), true
+	}
+	statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if cs.State.Waiting != nil && failedWaitingReasons[cs.State.Waiting.Reason] {
+			return cs.State.Waiting.Reason, true
+		}
+	}
+	return "", false
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// clusterServiceStatusName returns a valid cluster wide unique name for the service of the namespace
+func clusterServiceStatusName(namespace, service string) string {
+	name := namespace + "." + strings.Trim(strings.ReplaceAll(sanitize(service, true), ".", "-"), "-")
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-.")
+	}
+	return name
+}
+
+// healthServiceLabelValue returns the HealthService name as a label value. A name longer than a label
+// value is truncated and suffixed with a hash of the name, so that it still selects one HealthService.
+func healthServiceLabelValue(name string) string {
+	if len(name) <= validation.LabelValueMaxLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:10]
+	return strings.TrimRight(name[:validation.LabelValueMaxLength-len(hash)-1], "-.") + "-" + hash
+}
+
+// labelValue returns a valid label value for s
+func labelValue(s string) string {
+	v := sanitize(s, false)
+	if len(v) > 63 {
+		v = v[:63]
+	}
+	return strings.Trim(v, "-_.")
+}
+
+// sanitize replaces the characters not allowed in object names or label values with '-'
+func sanitize(s string, lower bool) string {
+	if lower {
+		s = strings.ToLower(s)
+	}
+	return strings.Map(func(c rune) rune {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '.':
+			return c
+		case !lower && (c >= 'A' && c <= 'Z' || c == '_'):
+			return c
+		}
+		return '-'
+	}, s)
+}
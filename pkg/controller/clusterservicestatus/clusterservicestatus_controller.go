@@ -0,0 +1,296 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package clusterservicestatus
+
+import (
+	"context"
+	"reflect"
+
+	clusterhealthv1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/clusterhealth/v1"
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_clusterservicestatus")
+
+// cleanupFinalizer keeps a deleted HealthService until its ClusterServiceStatus objects are deleted,
+// they are cluster scoped and can't be garbage collected with it
+const cleanupFinalizer = "clusterhealth.ibm.com/clusterservicestatus-cleanup"
+
+// Add creates a new ClusterServiceStatus Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileClusterServiceStatus{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler.
+// The ClusterServiceStatus objects are computed per HealthService, so every request is a HealthService.
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("clusterservicestatus-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to HealthService, which defines how the services are found
+	err = c.Watch(&source.Kind{Type: &operatorv1alpha1.HealthService{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to Pods and requeue the HealthServices of the same namespace
+	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			return healthServiceRequests(mgr.GetClient(), a.Meta.GetNamespace())
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to ClusterServiceStatus and requeue the HealthService which manages it
+	err = c.Watch(&source.Kind{Type: &clusterhealthv1.ClusterServiceStatus{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			labels := a.Meta.GetLabels()
+			if labels[managedByLabel] != managedByValue {
+				return nil
+			}
+			// the label holds a hash of the names longer than a label value
+			name, ok := a.Meta.GetAnnotations()[healthServiceNameAnnotation]
+			if !ok {
+				name = labels[healthServiceNameLabel]
+			}
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{
+				Namespace: labels[healthServiceNamespaceLabel],
+				Name:      name,
+			}}}
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// healthServiceRequests returns a request for every HealthService in the namespace
+func healthServiceRequests(c client.Client, namespace string) []reconcile.Request {
+	healthServiceList := &operatorv1alpha1.HealthServiceList{}
+	if err := c.List(context.TODO(), healthServiceList, client.InNamespace(namespace)); err != nil {
+		log.Error(err, "Failed to list HealthServices", "Namespace", namespace)
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, h := range healthServiceList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: h.Namespace, Name: h.Name},
+		})
+	}
+	return requests
+}
+
+// blank assignment to verify that ReconcileClusterServiceStatus implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileClusterServiceStatus{}
+
+// ReconcileClusterServiceStatus reconciles the ClusterServiceStatus objects of a HealthService
+type ReconcileClusterServiceStatus struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+// Reconcile computes the status of every service found in the namespace of a HealthService and
+// creates, updates or deletes the matching ClusterServiceStatus objects.
+// Note:
+// The Controller will requeue the Request to be processed again if the returned error is non-nil or
+// Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
+func (r *ReconcileClusterServiceStatus) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.Info("Reconciling ClusterServiceStatus")
+
+	// Fetch the HealthService instance
+	healthService := &operatorv1alpha1.HealthService{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, healthService)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// A HealthService deleted before it had the finalizer
+			reqLogger.Info("HealthService resource not found. Deleting its ClusterServiceStatus objects")
+			return reconcile.Result{}, r.deleteStaleClusterServiceStatuses(request.NamespacedName, nil)
+		}
+		// Error reading the object - requeue the request.
+		reqLogger.Error(err, "Failed to get HealthService")
+		return reconcile.Result{}, err
+	}
+
+	if !healthService.DeletionTimestamp.IsZero() {
+		if !hasFinalizer(healthService, cleanupFinalizer) {
+			return reconcile.Result{}, nil
+		}
+		reqLogger.Info("HealthService is deleted. Deleting its ClusterServiceStatus objects")
+		if err := r.deleteStaleClusterServiceStatuses(request.NamespacedName, nil); err != nil {
+			return reconcile.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(healthService, cleanupFinalizer)
+		return reconcile.Result{}, r.client.Update(context.TODO(), healthService)
+	}
+	if !hasFinalizer(healthService, cleanupFinalizer) {
+		controllerutil.AddFinalizer(healthService, cleanupFinalizer)
+		if err := r.client.Update(context.TODO(), healthService); err != nil {
+			reqLogger.Error(err, "Failed to add the finalizer of the HealthService")
+			return reconcile.Result{}, err
+		}
+	}
+
+	podList := &corev1.PodList{}
+	if err := r.client.List(context.TODO(), podList, client.InNamespace(healthService.Namespace)); err != nil {
+		reqLogger.Error(err, "Failed to list pods")
+		return reconcile.Result{}, err
+	}
+
+	desired := desiredClusterServiceStatuses(healthService, podList.Items)
+	for _, css := range desired {
+		if err := r.createOrUpdateClusterServiceStatus(healthService, css); err != nil {
+			reqLogger.Error(err, "Failed to create or update ClusterServiceStatus", "ClusterServiceStatus.Name", css.Name)
+			return reconcile.Result{}, err
+		}
+	}
+
+	if err := r.deleteStaleClusterServiceStatuses(request.NamespacedName, desired); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// createOrUpdateClusterServiceStatus creates the ClusterServiceStatus of the HealthService, or updates it.
+// The ClusterServiceStatus of a service is only updated by the HealthService which created it, another
+// HealthService of the namespace which finds the same service leaves it as is.
+func (r *ReconcileClusterServiceStatus) createOrUpdateClusterServiceStatus(h *operatorv1alpha1.HealthService,
+	desired *clusterhealthv1.ClusterServiceStatus) error {
+	current := &clusterhealthv1.ClusterServiceStatus{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: desired.Name}, current)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Info("Creating ClusterServiceStatus", "ClusterServiceStatus.Name", desired.Name)
+			return r.client.Create(context.TODO(), desired)
+		}
+		return err
+	}
+	if !managedFor(current, h) {
+		log.Info("Skip ClusterServiceStatus: it is not managed for the HealthService", "ClusterServiceStatus.Name", current.Name,
+			"HealthService.Namespace", h.Namespace, "HealthService.Name", h.Name)
+		return nil
+	}
+
+	updated := current.DeepCopy()
+	if updated.Labels == nil {
+		updated.Labels = map[string]string{}
+	}
+	for k, v := range desired.Labels {
+		updated.Labels[k] = v
+	}
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	for k, v := range desired.Annotations {
+		updated.Annotations[k] = v
+	}
+	updated.Status = desired.Status
+	if reflect.DeepEqual(current, updated) {
+		return nil
+	}
+
+	log.Info("Updating ClusterServiceStatus", "ClusterServiceStatus.Name", desired.Name)
+	return r.client.Update(context.TODO(), updated)
+}
+
+// managedFor returns whether the ClusterServiceStatus is managed by the operator for the HealthService
+func managedFor(css *clusterhealthv1.ClusterServiceStatus, h *operatorv1alpha1.HealthService) bool {
+	labels := css.GetLabels()
+	if labels[managedByLabel] != managedByValue || labels[healthServiceNamespaceLabel] != h.Namespace {
+		return false
+	}
+	if name, ok := css.GetAnnotations()[healthServiceNameAnnotation]; ok {
+		return name == h.Name
+	}
+	return labels[healthServiceNameLabel] == healthServiceLabelValue(h.Name)
+}
+
+func hasFinalizer(obj metav1.Object, finalizer string) bool {
+	for _, f := range obj.GetFinalizers() {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteStaleClusterServiceStatuses deletes the ClusterServiceStatus objects managed for the HealthService
+// which are not in the desired list anymore
+func (r *ReconcileClusterServiceStatus) deleteStaleClusterServiceStatuses(healthService types.NamespacedName,
+	desired []*clusterhealthv1.ClusterServiceStatus) error {
+	list := &clusterhealthv1.ClusterServiceStatusList{}
+	if err := r.client.List(context.TODO(), list, client.MatchingLabels{
+		managedByLabel:              managedByValue,
+		healthServiceNamespaceLabel: healthService.Namespace,
+		healthServiceNameLabel:      healthServiceLabelValue(healthService.Name),
+	}); err != nil {
+		log.Error(err, "Failed to list ClusterServiceStatus")
+		return err
+	}
+
+	keep := map[string]bool{}
+	for _, css := range desired {
+		keep[css.Name] = true
+	}
+
+	for i := range list.Items {
+		css := &list.Items[i]
+		if keep[css.Name] {
+			continue
+		}
+		if name, ok := css.GetAnnotations()[healthServiceNameAnnotation]; ok && name != healthService.Name {
+			continue
+		}
+		log.Info("Deleting stale ClusterServiceStatus", "ClusterServiceStatus.Name", css.Name)
+		if err := r.client.Delete(context.TODO(), css); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, "Failed to delete ClusterServiceStatus", "ClusterServiceStatus.Name", css.Name)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package clusterservicestatus
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	clusterhealthv1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/clusterhealth/v1"
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTestReconciler(t *testing.T, objs ...runtime.Object) *ReconcileClusterServiceStatus {
+	s := runtime.NewScheme()
+	if err := corev1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := operatorv1alpha1.SchemeBuilder.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := clusterhealthv1.SchemeBuilder.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return &ReconcileClusterServiceStatus{client: fake.NewFakeClientWithScheme(s, objs...), scheme: s}
+}
+
+func newHealthService(name string) *operatorv1alpha1.HealthService {
+	return &operatorv1alpha1.HealthService{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ibm-common-services"},
+	}
+}
+
+func TestCreateOrUpdateClusterServiceStatus(t *testing.T) {
+	owner := newHealthService("system-healthcheck-service")
+	other := newHealthService("other-healthcheck-service")
+	desired := func(h *operatorv1alpha1.HealthService, state string) *clusterhealthv1.ClusterServiceStatus {
+		css := newClusterServiceStatus(h, &serviceStatus{name: "auth-idp"})
+		css.Status.CurrentState = state
+		return css
+	}
+	unmanaged := desired(owner, clusterhealthv1.StatePending)
+	delete(unmanaged.Labels, managedByLabel)
+
+	tests := []struct {
+		name     string
+		existing *clusterhealthv1.ClusterServiceStatus
+		h        *operatorv1alpha1.HealthService
+		want     string
+	}{
+		{"created", nil, owner, clusterhealthv1.StateRunning},
+		{"updated by its HealthService", desired(owner, clusterhealthv1.StatePending), owner, clusterhealthv1.StateRunning},
+		{"managed for another HealthService", desired(other, clusterhealthv1.StatePending), owner, clusterhealthv1.StatePending},
+		{"not managed by the operator", unmanaged, owner, clusterhealthv1.StatePending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []runtime.Object
+			if tt.existing != nil {
+				objs = append(objs, tt.existing)
+			}
+			r := newTestReconciler(t, objs...)
+			css := desired(tt.h, clusterhealthv1.StateRunning)
+			if err := r.createOrUpdateClusterServiceStatus(tt.h, css); err != nil {
+				t.Fatal(err)
+			}
+
+			got := &clusterhealthv1.ClusterServiceStatus{}
+			if err := r.client.Get(context.TODO(), types.NamespacedName{Name: css.Name}, got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.CurrentState != tt.want {
+				t.Errorf("CurrentState = %q, want %q", got.Status.CurrentState, tt.want)
+			}
+			if tt.want == clusterhealthv1.StatePending && !reflect.DeepEqual(got.Labels, tt.existing.Labels) {
+				t.Errorf("labels = %v, want %v", got.Labels, tt.existing.Labels)
+			}
+		})
+	}
+}
+
+func TestReconcileFinalizer(t *testing.T) {
+	h := newHealthService("system-healthcheck-service")
+	css := newClusterServiceStatus(h, &serviceStatus{name: "auth-idp"})
+	r := newTestReconciler(t, h, css)
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: h.Namespace, Name: h.Name}}
+
+	if _, err := r.Reconcile(request); err != nil {
+		t.Fatal(err)
+	}
+	got := &operatorv1alpha1.HealthService{}
+	if err := r.client.Get(context.TODO(), request.NamespacedName, got); err != nil {
+		t.Fatal(err)
+	}
+	if !hasFinalizer(got, cleanupFinalizer) {
+		t.Fatalf("finalizers = %v, want %s", got.Finalizers, cleanupFinalizer)
+	}
+
+	now := metav1.Now()
+	got.DeletionTimestamp = &now
+	if err := r.client.Update(context.TODO(), got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(request); err != nil {
+		t.Fatal(err)
+	}
+	got = &operatorv1alpha1.HealthService{}
+	if err := r.client.Get(context.TODO(), request.NamespacedName, got); err != nil {
+		t.Fatal(err)
+	}
+	if hasFinalizer(got, cleanupFinalizer) {
+		t.Errorf("finalizers = %v, want no %s", got.Finalizers, cleanupFinalizer)
+	}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: css.Name}, &clusterhealthv1.ClusterServiceStatus{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("ClusterServiceStatus is not deleted: %v", err)
+	}
+}
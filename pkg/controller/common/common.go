@@ -23,15 +23,6 @@ import (
 	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
 )
 
-// GetPodNames returns the pod names of the array of pods passed in
-func GetPodNames(pods []corev1.Pod) []string {
-	var podNames []string
-	for _, pod := range pods {
-		podNames = append(podNames, pod.Name)
-	}
-	return podNames
-}
-
 // GetResources returns ResourceRequirements
 func GetResources(res *operatorv1alpha1.Resources) *corev1.ResourceRequirements {
 	var (
@@ -0,0 +1,113 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConfigHashAnnotation is the pod template annotation holding the hash of the mounted configmaps,
+// so that a change of their data rolls the pods out
+const ConfigHashAnnotation = "healthcheck.ibm.com/config-hash"
+
+// MountedConfigMaps returns the names of the configmaps mounted by the pod, in volume order
+func MountedConfigMaps(spec *corev1.PodSpec) []string {
+	names := []string{}
+	for _, v := range spec.Volumes {
+		if v.ConfigMap != nil {
+			names = append(names, v.ConfigMap.Name)
+		}
+		if v.Projected != nil {
+			for _, s := range v.Projected.Sources {
+				if s.ConfigMap != nil {
+					names = append(names, s.ConfigMap.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// ConfigMapsHash returns the hash of the data of the configmaps. A missing configmap hashes as empty,
+// the pod then waits for the configmap and is rolled out again once it is created.
+func ConfigMapsHash(c client.Client, namespace string, names []string) (string, error) {
+	h := sha256.New()
+	for _, name := range names {
+		cm := &corev1.ConfigMap{}
+		err := c.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, cm)
+		if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+		writeHashField(h, name)
+
+		keys := []string{}
+		for k := range cm.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			writeHashField(h, k)
+			writeHashField(h, cm.Data[k])
+		}
+
+		keys = []string{}
+		for k := range cm.BinaryData {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			writeHashField(h, k)
+			writeHashField(h, string(cm.BinaryData[k]))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SetConfigHash sets the ConfigHashAnnotation of the pod template from the configmaps it mounts.
+// Nothing is set if the pod doesn't mount any configmap.
+func SetConfigHash(c client.Client, namespace string, template *corev1.PodTemplateSpec) error {
+	names := MountedConfigMaps(&template.Spec)
+	if len(names) == 0 {
+		return nil
+	}
+	hash, err := ConfigMapsHash(c, namespace, names)
+	if err != nil {
+		return err
+	}
+	annotations := map[string]string{}
+	for k, v := range template.Annotations {
+		annotations[k] = v
+	}
+	annotations[ConfigHashAnnotation] = hash
+	template.Annotations = annotations
+	return nil
+}
+
+// writeHashField writes a length prefixed field, so that the concatenation of fields is unambiguous
+func writeHashField(h io.Writer, s string) {
+	h.Write([]byte{byte(len(s) >> 24), byte(len(s) >> 16), byte(len(s) >> 8), byte(len(s))})
+	h.Write([]byte(s))
+}
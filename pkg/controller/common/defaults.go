@@ -0,0 +1,141 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// defaultVolumeMode is the mode of the files of the configmap, secret, projected and downward API volumes
+const defaultVolumeMode = int32(0644)
+
+// setServerDefaults sets the defaults the API server sets in the fields the operator manages, as the
+// v1 defaulting functions of Kubernetes do
+func setServerDefaults(obj runtime.Object) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		setPodSpecDefaults(&o.Spec.Template.Spec)
+	case *appsv1.StatefulSet:
+		setPodSpecDefaults(&o.Spec.Template.Spec)
+	case *corev1.Service:
+		setServiceDefaults(&o.Spec)
+	}
+}
+
+func setPodSpecDefaults(spec *corev1.PodSpec) {
+	for i := range spec.InitContainers {
+		setContainerDefaults(&spec.InitContainers[i])
+	}
+	for i := range spec.Containers {
+		setContainerDefaults(&spec.Containers[i])
+	}
+	for i := range spec.Volumes {
+		setVolumeDefaults(&spec.Volumes[i].VolumeSource)
+	}
+}
+
+func setContainerDefaults(c *corev1.Container) {
+	if c.TerminationMessagePath == "" {
+		c.TerminationMessagePath = corev1.TerminationMessagePathDefault
+	}
+	if c.TerminationMessagePolicy == "" {
+		c.TerminationMessagePolicy = corev1.TerminationMessageReadFile
+	}
+	if c.ImagePullPolicy == "" {
+		c.ImagePullPolicy = defaultPullPolicy(c.Image)
+	}
+	for i := range c.Ports {
+		if c.Ports[i].Protocol == "" {
+			c.Ports[i].Protocol = corev1.ProtocolTCP
+		}
+	}
+	for i := range c.Env {
+		if from := c.Env[i].ValueFrom; from != nil && from.FieldRef != nil && from.FieldRef.APIVersion == "" {
+			from.FieldRef.APIVersion = "v1"
+		}
+	}
+	setProbeDefaults(c.LivenessProbe)
+	setProbeDefaults(c.ReadinessProbe)
+	setProbeDefaults(c.StartupProbe)
+}
+
+// defaultPullPolicy is Always for the latest tag or no tag, else IfNotPresent
+func defaultPullPolicy(image string) corev1.PullPolicy {
+	if strings.Contains(image, "@") {
+		return corev1.PullIfNotPresent
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.LastIndex(name, ":"); i < 0 || name[i+1:] == "latest" {
+		return corev1.PullAlways
+	}
+	return corev1.PullIfNotPresent
+}
+
+func setProbeDefaults(p *corev1.Probe) {
+	if p == nil {
+		return
+	}
+	if p.TimeoutSeconds == 0 {
+		p.TimeoutSeconds = 1
+	}
+	if p.PeriodSeconds == 0 {
+		p.PeriodSeconds = 10
+	}
+	if p.SuccessThreshold == 0 {
+		p.SuccessThreshold = 1
+	}
+	if p.FailureThreshold == 0 {
+		p.FailureThreshold = 3
+	}
+	if p.HTTPGet != nil && p.HTTPGet.Scheme == "" {
+		p.HTTPGet.Scheme = corev1.URISchemeHTTP
+	}
+}
+
+func setVolumeDefaults(v *corev1.VolumeSource) {
+	mode := defaultVolumeMode
+	switch {
+	case v.ConfigMap != nil && v.ConfigMap.DefaultMode == nil:
+		v.ConfigMap.DefaultMode = &mode
+	case v.Secret != nil && v.Secret.DefaultMode == nil:
+		v.Secret.DefaultMode = &mode
+	case v.Projected != nil && v.Projected.DefaultMode == nil:
+		v.Projected.DefaultMode = &mode
+	case v.DownwardAPI != nil && v.DownwardAPI.DefaultMode == nil:
+		v.DownwardAPI.DefaultMode = &mode
+	case v.HostPath != nil && v.HostPath.Type == nil:
+		unset := corev1.HostPathUnset
+		v.HostPath.Type = &unset
+	}
+}
+
+func setServiceDefaults(spec *corev1.ServiceSpec) {
+	for i := range spec.Ports {
+		port := &spec.Ports[i]
+		if port.Protocol == "" {
+			port.Protocol = corev1.ProtocolTCP
+		}
+		if port.TargetPort == (intstr.IntOrString{}) {
+			port.TargetPort = intstr.FromInt(int(port.Port))
+		}
+	}
+}
@@ -0,0 +1,365 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+)
+
+var (
+	// RouteGroupKind is the OpenShift Route kind
+	RouteGroupKind = schema.GroupKind{Group: "route.openshift.io", Kind: "Route"}
+	// HTTPRouteGroupKind is the Gateway API HTTPRoute kind
+	HTTPRouteGroupKind = schema.GroupKind{Group: "gateway.networking.k8s.io", Kind: "HTTPRoute"}
+)
+
+// ExposureTarget is the service exposed by a Route or an HTTPRoute
+type ExposureTarget struct {
+	// Name is the name of the service, and of the Route or HTTPRoute
+	Name      string
+	Namespace string
+	Labels    map[string]string
+	Path      string
+	Port      int32
+}
+
+// ServedKind returns the preferred version of the kind, false if the kind is not served by the cluster
+func ServedKind(mapper meta.RESTMapper, gk schema.GroupKind) (schema.GroupVersionKind, bool) {
+	mapping, err := mapper.RESTMapping(gk)
+	if err != nil {
+		return schema.GroupVersionKind{}, false
+	}
+	return mapping.GroupVersionKind, true
+}
+
+// ServedExposureKinds returns the Route and HTTPRoute kinds served by the cluster
+func ServedExposureKinds(mapper meta.RESTMapper) []schema.GroupVersionKind {
+	kinds := []schema.GroupVersionKind{}
+	for _, gk := range []schema.GroupKind{RouteGroupKind, HTTPRouteGroupKind} {
+		if gvk, ok := ServedKind(mapper, gk); ok {
+			kinds = append(kinds, gvk)
+		}
+	}
+	return kinds
+}
+
+// ResolveExposureMode returns the exposure mode to use. An empty mode is Ingress, the Auto mode
+// is resolved from the kinds served by the cluster. It is an error to ask for a kind which is not served.
+func ResolveExposureMode(mapper meta.RESTMapper, mode operatorv1alpha1.ExposureMode) (operatorv1alpha1.ExposureMode, error) {
+	_, hasRoute := ServedKind(mapper, RouteGroupKind)
+	_, hasHTTPRoute := ServedKind(mapper, HTTPRouteGroupKind)
+
+	switch mode {
+	case "", operatorv1alpha1.ExposureIngress:
+		return operatorv1alpha1.ExposureIngress, nil
+	case operatorv1alpha1.ExposureAuto:
+		if hasRoute {
+			return operatorv1alpha1.ExposureRoute, nil
+		}
+		if hasHTTPRoute {
+			return operatorv1alpha1.ExposureHTTPRoute, nil
+		}
+		return operatorv1alpha1.ExposureIngress, nil
+	case operatorv1alpha1.ExposureRoute:
+		if !hasRoute {
+			return mode, fmt.Errorf("exposure mode %s: %s is not served by the cluster", mode, RouteGroupKind)
+		}
+		return mode, nil
+	case operatorv1alpha1.ExposureHTTPRoute:
+		if !hasHTTPRoute {
+			return mode, fmt.Errorf("exposure mode %s: %s is not served by the cluster", mode, HTTPRouteGroupKind)
+		}
+		return mode, nil
+	}
+	return mode, fmt.Errorf("unknown exposure mode %q", mode)
+}
+
+// DesiredRoute returns the OpenShift Route of the target.
+// The router rewrites the path to "/" like the default Ingress does.
+func DesiredRoute(gvk schema.GroupVersionKind, t ExposureTarget, route *operatorv1alpha1.RouteExposure) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"path": t.Path,
+		"to": map[string]interface{}{
+			"kind":   "Service",
+			"name":   t.Name,
+			"weight": int64(100),
+		},
+		"port": map[string]interface{}{
+			"targetPort": int64(t.Port),
+		},
+		"wildcardPolicy": "None",
+	}
+	if route.Host != "" {
+		spec["host"] = route.Host
+	}
+	spec["tls"] = nil
+	if route.Termination != "" {
+		spec["tls"] = map[string]interface{}{
+			"termination":                   route.Termination,
+			"insecureEdgeTerminationPolicy": "Redirect",
+		}
+	}
+
+	u := newExposureObject(gvk, t)
+	u.SetAnnotations(map[string]string{"haproxy.router.openshift.io/rewrite-target": "/"})
+	u.Object["spec"] = spec
+	return u
+}
+
+// DesiredHTTPRoute returns the Gateway API HTTPRoute of the target.
+// The path prefix is replaced with "/" like the default Ingress does.
+func DesiredHTTPRoute(gvk schema.GroupVersionKind, t ExposureTarget, route *operatorv1alpha1.HTTPRouteExposure) *unstructured.Unstructured {
+	parentRefs := []interface{}{}
+	for _, ref := range route.ParentRefs {
+		parentRef := map[string]interface{}{"name": ref.Name}
+		if ref.Namespace != "" {
+			parentRef["namespace"] = ref.Namespace
+		}
+		if ref.SectionName != "" {
+			parentRef["sectionName"] = ref.SectionName
+		}
+		parentRefs = append(parentRefs, parentRef)
+	}
+
+	spec := map[string]interface{}{
+		"parentRefs": parentRefs,
+		"rules": []interface{}{
+			map[string]interface{}{
+				"matches": []interface{}{
+					map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":  "PathPrefix",
+							"value": t.Path,
+						},
+					},
+				},
+				"filters": []interface{}{
+					map[string]interface{}{
+						"type": "URLRewrite",
+						"urlRewrite": map[string]interface{}{
+							"path": map[string]interface{}{
+								"type":               "ReplacePrefixMatch",
+								"replacePrefixMatch": "/",
+							},
+						},
+					},
+				},
+				"backendRefs": []interface{}{
+					map[string]interface{}{
+						"name": t.Name,
+						"port": int64(t.Port),
+					},
+				},
+			},
+		},
+	}
+	spec["hostnames"] = nil
+	if len(route.Hostnames) > 0 {
+		hostnames := []interface{}{}
+		for _, h := range route.Hostnames {
+			hostnames = append(hostnames, h)
+		}
+		spec["hostnames"] = hostnames
+	}
+
+	u := newExposureObject(gvk, t)
+	u.Object["spec"] = spec
+	return u
+}
+
+func newExposureObject(gvk schema.GroupVersionKind, t ExposureTarget) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	u.SetName(t.Name)
+	u.SetNamespace(t.Namespace)
+	u.SetLabels(t.Labels)
+	return u
+}
+
+// CreateOrUpdateExposure creates the Route or HTTPRoute, or updates its labels, annotations,
+// owner references and spec if they changed. The fields which are not desired, such as the host
+// generated by the OpenShift router, are kept.
+func CreateOrUpdateExposure(c client.Client, owner metav1.Object, desired *unstructured.Unstructured) error {
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(desired.GroupVersionKind())
+	err := c.Get(context.TODO(), types.NamespacedName{Name: desired.GetName(), Namespace: desired.GetNamespace()}, current)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return c.Create(context.TODO(), desired)
+		}
+		return err
+	}
+	if err := CheckOwnership(owner, current); err != nil {
+		return err
+	}
+
+	updated := current.DeepCopy()
+	updated.SetLabels(desired.GetLabels())
+	updated.SetAnnotations(desired.GetAnnotations())
+	updated.SetOwnerReferences(desired.GetOwnerReferences())
+	updated.Object["spec"] = mergeFields(updated.Object["spec"], desired.Object["spec"])
+	if reflect.DeepEqual(current.Object, updated.Object) {
+		return nil
+	}
+	return c.Update(context.TODO(), updated)
+}
+
+// ReconcileExposure creates or updates the Route or HTTPRoute of the target in the resolved exposure
+// mode, and deletes the Route and HTTPRoute of the other modes. The Ingress is managed by the owner.
+func ReconcileExposure(c client.Client, mapper meta.RESTMapper, scheme *runtime.Scheme, owner metav1.Object,
+	mode operatorv1alpha1.ExposureMode, exposure *operatorv1alpha1.Exposure, t ExposureTarget) error {
+	exposures := []struct {
+		mode    operatorv1alpha1.ExposureMode
+		gk      schema.GroupKind
+		desired func(schema.GroupVersionKind) *unstructured.Unstructured
+	}{
+		{operatorv1alpha1.ExposureRoute, RouteGroupKind, func(gvk schema.GroupVersionKind) *unstructured.Unstructured {
+			return DesiredRoute(gvk, t, &exposure.Route)
+		}},
+		{operatorv1alpha1.ExposureHTTPRoute, HTTPRouteGroupKind, func(gvk schema.GroupVersionKind) *unstructured.Unstructured {
+			return DesiredHTTPRoute(gvk, t, &exposure.HTTPRoute)
+		}},
+	}
+	for _, e := range exposures {
+		if mode != e.mode {
+			if err := DeleteExposure(c, mapper, owner, e.gk, t.Name, t.Namespace); err != nil {
+				return fmt.Errorf("failed to delete %s %s/%s: %v", e.gk.Kind, t.Namespace, t.Name, err)
+			}
+			continue
+		}
+
+		gvk, _ := ServedKind(mapper, e.gk)
+		desired := e.desired(gvk)
+		if err := controllerutil.SetControllerReference(owner, desired, scheme); err != nil {
+			return err
+		}
+		if err := CreateOrUpdateExposure(c, owner, desired); err != nil {
+			return fmt.Errorf("failed to create or update %s %s/%s: %v", e.gk.Kind, t.Namespace, t.Name, err)
+		}
+	}
+	return nil
+}
+
+// ExposureState is the observed state of the Ingress, Route or HTTPRoute of a service
+type ExposureState struct {
+	// Ready is true when the object of the exposure mode is created, or the Ingress is disabled
+	Ready bool
+	// Failed is true when the exposure mode or the object can't be resolved
+	Failed  bool
+	Reason  string
+	Message string
+}
+
+// GetExposureState returns whether the Ingress, Route or HTTPRoute name of the exposure mode is created
+func GetExposureState(c client.Client, mapper meta.RESTMapper, exposure *operatorv1alpha1.Exposure,
+	ingress *operatorv1alpha1.Ingress, name, namespace string) ExposureState {
+	mode, err := ResolveExposureMode(mapper, exposure.Mode)
+	if err != nil {
+		return ExposureState{Failed: true, Reason: "ExposureFailed", Message: err.Error()}
+	}
+
+	var obj runtime.Object
+	var kind string
+	switch mode {
+	case operatorv1alpha1.ExposureRoute, operatorv1alpha1.ExposureHTTPRoute:
+		gk := RouteGroupKind
+		if mode == operatorv1alpha1.ExposureHTTPRoute {
+			gk = HTTPRouteGroupKind
+		}
+		gvk, _ := ServedKind(mapper, gk)
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		obj, kind = u, gk.Kind
+	default:
+		if ingress.Disabled {
+			return ExposureState{Ready: true, Reason: "IngressDisabled", Message: "ingress is disabled"}
+		}
+		obj, kind = &networkingv1.Ingress{}, "Ingress"
+	}
+
+	err = c.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, obj)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ExposureState{Reason: kind + "Pending",
+				Message: fmt.Sprintf("%s %q is not created yet", strings.ToLower(kind), name)}
+		}
+		return ExposureState{Failed: true, Reason: kind + "Unknown", Message: err.Error()}
+	}
+	return ExposureState{Ready: true, Reason: kind + "Created", Message: fmt.Sprintf("%s %q is created", strings.ToLower(kind), name)}
+}
+
+// DeleteExposure deletes the Route or HTTPRoute of the owner, if the kind is served by the cluster
+func DeleteExposure(c client.Client, mapper meta.RESTMapper, owner metav1.Object, gk schema.GroupKind, name, namespace string) error {
+	gvk, ok := ServedKind(mapper, gk)
+	if !ok {
+		return nil
+	}
+
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(gvk)
+	err := c.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, current)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !metav1.IsControlledBy(current, owner) {
+		return nil
+	}
+	if err := c.Delete(context.TODO(), current); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// mergeFields returns current in which the fields of desired are set, maps are merged recursively.
+// A nil desired field removes the field.
+func mergeFields(current, desired interface{}) interface{} {
+	currentMap, ok := current.(map[string]interface{})
+	if !ok {
+		return desired
+	}
+	desiredMap, ok := desired.(map[string]interface{})
+	if !ok {
+		return desired
+	}
+	for k, v := range desiredMap {
+		if v == nil {
+			delete(currentMap, k)
+			continue
+		}
+		currentMap[k] = mergeFields(currentMap[k], v)
+	}
+	return currentMap
+}
@@ -0,0 +1,146 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	constant "github.com/IBM/ibm-healthcheck-operator/pkg/controller/constant"
+)
+
+// OperatorImage returns the image of the operator and its pull policy, from the operator env var,
+// else from the main container of the operator pod
+func OperatorImage(c client.Client) (string, corev1.PullPolicy, error) {
+	if os.Getenv(constant.OperatorImageEnv) != "" {
+		image, pullPolicy := ResolveImage(&operatorv1alpha1.Image{}, constant.OperatorImageEnv, "")
+		return image, pullPolicy, nil
+	}
+	pod, err := k8sutil.GetPod(context.TODO(), c, operatorNamespace())
+	if err != nil {
+		return "", "", err
+	}
+	podImage := pod.Spec.Containers[0].Image
+	for _, container := range pod.Spec.Containers {
+		if container.Name == os.Getenv(k8sutil.OperatorNameEnvVar) {
+			podImage = container.Image
+		}
+	}
+	image, pullPolicy := ResolveImage(&operatorv1alpha1.Image{}, constant.OperatorImageEnv, podImage)
+	return image, pullPolicy, nil
+}
+
+// ResolveImage returns the image of an operand container and its pull policy. The image is the
+// image of the operator env var envName, else defaultImage, in which the repository, tag and
+// digest set in custom are replaced. A digest is preferred to a tag. The pull policy is the one
+// of custom, else IfNotPresent for an image pinned by digest or by a tag other than latest.
+// The registry is not queried, a tag is not resolved to a digest, see RunningImage.
+func ResolveImage(custom *operatorv1alpha1.Image, envName, defaultImage string) (string, corev1.PullPolicy) {
+	image := os.Getenv(envName)
+	if image == "" {
+		image = defaultImage
+	}
+
+	repository, tag, digest := splitImage(image)
+	if custom.Repository != "" {
+		// a mirror of the repository keeps its tag and digest, unless the repository is set with its own
+		r, t, d := splitImage(custom.Repository)
+		repository = r
+		if t != "" || d != "" {
+			tag, digest = t, d
+		}
+	}
+	if custom.Tag != "" {
+		tag, digest = custom.Tag, ""
+		// a tag which is a digest pins the image as a digest does
+		if strings.Contains(custom.Tag, ":") {
+			tag, digest = "", custom.Tag
+		}
+	}
+	if custom.Digest != "" {
+		digest = custom.Digest
+	}
+
+	resolved := repository
+	switch {
+	case digest != "":
+		resolved += "@" + digest
+	case tag != "":
+		resolved += ":" + tag
+	}
+	return resolved, pullPolicy(custom.PullPolicy, tag, digest)
+}
+
+// RunningImage returns the image pinned to the digest run by the main container of the pods created with
+// the image, once they all run the same digest. It is the image as is until then.
+func RunningImage(image string, pods []corev1.Pod) string {
+	digest := ""
+	for i := range pods {
+		pod := &pods[i]
+		if len(pod.Spec.Containers) == 0 || pod.Spec.Containers[0].Image != image {
+			continue
+		}
+		podDigest := ""
+		for _, cs := range pod.Status.ContainerStatuses {
+			// the image id is repository@digest once pulled from a registry, else the local image id
+			if at := strings.LastIndex(cs.ImageID, "@"); cs.Name == pod.Spec.Containers[0].Name && at >= 0 {
+				podDigest = cs.ImageID[at+1:]
+			}
+		}
+		if podDigest == "" || (digest != "" && podDigest != digest) {
+			return image
+		}
+		digest = podDigest
+	}
+	if digest == "" {
+		return image
+	}
+	repository, _, _ := splitImage(image)
+	return repository + "@" + digest
+}
+
+// pullPolicy returns the custom pull policy when it is valid, else the default pull policy of the
+// image, as the API server defaults it
+func pullPolicy(custom, tag, digest string) corev1.PullPolicy {
+	switch policy := corev1.PullPolicy(custom); policy {
+	case corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+		return policy
+	}
+	if digest == "" && (tag == "" || tag == "latest") {
+		return corev1.PullAlways
+	}
+	return corev1.PullIfNotPresent
+}
+
+// splitImage returns the repository, tag and digest of an image reference
+func splitImage(image string) (repository, tag, digest string) {
+	repository = image
+	if i := strings.Index(repository, "@"); i >= 0 {
+		repository, digest = repository[:i], repository[i+1:]
+	}
+	// a colon before the last slash separates the port of the registry
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, tag = repository[:i], repository[i+1:]
+	}
+	return repository, tag, digest
+}
@@ -0,0 +1,63 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestRunningImage(t *testing.T) {
+	const image = "icr.io/cpopen/cpfs/icp-memcached:3.10.23"
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	pod := func(image, imageID string) corev1.Pod {
+		return corev1.Pod{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "memcached", Image: image}}},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "memcached", Image: image, ImageID: imageID},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name string
+		pods []corev1.Pod
+		want string
+	}{
+		{"no pod", nil, image},
+		{"pulled", []corev1.Pod{pod(image, "docker-pullable://icr.io/cpopen/cpfs/icp-memcached@"+digest)},
+			"icr.io/cpopen/cpfs/icp-memcached@" + digest},
+		{"pod of the previous image", []corev1.Pod{
+			pod(image, "icr.io/cpopen/cpfs/icp-memcached@"+digest),
+			pod("icr.io/cpopen/cpfs/icp-memcached:3.10.22", "icr.io/cpopen/cpfs/icp-memcached@sha256:other"),
+		}, "icr.io/cpopen/cpfs/icp-memcached@" + digest},
+		{"pulling", []corev1.Pod{pod(image, "icr.io/cpopen/cpfs/icp-memcached@"+digest), pod(image, "")}, image},
+		{"different digests", []corev1.Pod{
+			pod(image, "icr.io/cpopen/cpfs/icp-memcached@"+digest),
+			pod(image, "icr.io/cpopen/cpfs/icp-memcached@sha256:other"),
+		}, image},
+		{"local image", []corev1.Pod{pod(image, "docker://sha256:abc")}, image},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RunningImage(image, tt.pods); got != tt.want {
+				t.Errorf("RunningImage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	networkingv1 "k8s.io/api/networking/v1"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	constant "github.com/IBM/ibm-healthcheck-operator/pkg/controller/constant"
+)
+
+// IngressAnnotations returns the annotations of an Ingress. The default annotations only apply when
+// no ingress class name is set, since they are specific to the ibm-icp-management controller.
+// The custom annotations override the default ones.
+func IngressAnnotations(ing *operatorv1alpha1.Ingress, defaults map[string]string) map[string]string {
+	annotations := map[string]string{}
+	if ing.IngressClassName == "" {
+		for k, v := range defaults {
+			annotations[k] = v
+		}
+	}
+	for k, v := range ing.Annotations {
+		annotations[k] = v
+	}
+	return annotations
+}
+
+// IngressSpec returns the spec of an Ingress routing the path, or defaultPath if not set,
+// of every host to the service port
+func IngressSpec(ing *operatorv1alpha1.Ingress, defaultPath, serviceName string, port int32) networkingv1.IngressSpec {
+	path := defaultPath
+	if ing.Path != "" {
+		path = ing.Path
+	}
+	pathType := networkingv1.PathType(constant.IngPathType)
+
+	http := &networkingv1.HTTPIngressRuleValue{
+		Paths: []networkingv1.HTTPIngressPath{
+			{
+				Path:     path,
+				PathType: &pathType,
+				Backend: networkingv1.IngressBackend{
+					Service: &networkingv1.IngressServiceBackend{
+						Name: serviceName,
+						Port: networkingv1.ServiceBackendPort{Number: port},
+					},
+				},
+			},
+		},
+	}
+
+	spec := networkingv1.IngressSpec{}
+	if len(ing.Hosts) == 0 {
+		spec.Rules = []networkingv1.IngressRule{
+			{IngressRuleValue: networkingv1.IngressRuleValue{HTTP: http}},
+		}
+	}
+	for _, host := range ing.Hosts {
+		spec.Rules = append(spec.Rules, networkingv1.IngressRule{
+			Host:             host,
+			IngressRuleValue: networkingv1.IngressRuleValue{HTTP: http.DeepCopy()},
+		})
+	}
+
+	if ing.IngressClassName != "" {
+		className := ing.IngressClassName
+		spec.IngressClassName = &className
+	}
+	if ing.TLSSecretName != "" {
+		spec.TLS = []networkingv1.IngressTLS{
+			{
+				Hosts:      append([]string{}, ing.Hosts...),
+				SecretName: ing.TLSSecretName,
+			},
+		}
+	}
+
+	return spec
+}
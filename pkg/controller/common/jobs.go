@@ -0,0 +1,80 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LatestJobPod returns the last pod created by the job, nil if there is none
+func LatestJobPod(c client.Client, job *batchv1.Job) (*corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	err := c.List(context.TODO(), podList, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name})
+	if err != nil {
+		return nil, err
+	}
+	var latest *corev1.Pod
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !metav1.IsControlledBy(pod, job) {
+			continue
+		}
+		if latest == nil || latest.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			latest = pod
+		}
+	}
+	return latest, nil
+}
+
+// JobFinished returns true when the job succeeded or failed
+func JobFinished(job *batchv1.Job) bool {
+	return JobCondition(job, batchv1.JobComplete) != nil || JobCondition(job, batchv1.JobFailed) != nil
+}
+
+// JobCondition returns the condition of the job with the given type when it is true
+func JobCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) *batchv1.JobCondition {
+	for i := range job.Status.Conditions {
+		c := &job.Status.Conditions[i]
+		if c.Type == conditionType && c.Status == corev1.ConditionTrue {
+			return c
+		}
+	}
+	return nil
+}
+
+// UnmarshalTerminationMessage decodes the JSON termination message of the container which succeeded in the pod
+func UnmarshalTerminationMessage(pod *corev1.Pod, v interface{}) error {
+	if pod == nil {
+		return fmt.Errorf("the job pod is not found")
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if t := cs.State.Terminated; t != nil && t.ExitCode == 0 {
+			if err := json.Unmarshal([]byte(t.Message), v); err != nil {
+				return fmt.Errorf("invalid termination message %q of pod %s: %v", t.Message, pod.Name, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("the pod %s has not succeeded", pod.Name)
+}
@@ -0,0 +1,117 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// RegistryMirrorsConfigMapName is the configmap, in the namespace of the operator, which maps
+	// registry prefixes to their mirror
+	RegistryMirrorsConfigMapName = "ibm-healthcheck-operator-registry-mirrors"
+	// registryMirrorsKey is the key of the mapping in the configmap, a YAML map such as
+	//   icr.io/cpopen: mirror.local/cpopen
+	registryMirrorsKey = "mirrors"
+)
+
+// RegistryMirrors maps image prefixes, a registry or a registry with a path, to their mirror
+type RegistryMirrors map[string]string
+
+// GetRegistryMirrors returns the registry mirrors of the operator, none if its configmap doesn't exist
+func GetRegistryMirrors(c client.Client) (RegistryMirrors, error) {
+	cm := &corev1.ConfigMap{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: RegistryMirrorsConfigMapName, Namespace: operatorNamespace()}, cm)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return RegistryMirrors{}, nil
+		}
+		return nil, err
+	}
+
+	mirrors := RegistryMirrors{}
+	if err := yaml.Unmarshal([]byte(cm.Data[registryMirrorsKey]), &mirrors); err != nil {
+		return nil, fmt.Errorf("invalid %s in configmap %s: %v", registryMirrorsKey, RegistryMirrorsConfigMapName, err)
+	}
+	for source, mirror := range mirrors {
+		if strings.TrimRight(source, "/") == "" || strings.TrimRight(mirror, "/") == "" {
+			return nil, fmt.Errorf("invalid %s in configmap %s: empty registry in %q: %q",
+				registryMirrorsKey, RegistryMirrorsConfigMapName, source, mirror)
+		}
+	}
+	return mirrors, nil
+}
+
+// Mirror returns the image pulled from the mirror of its longest matching prefix. A prefix
+// matches whole path components, icr.io/cp doesn't match icr.io/cpopen/must-gather.
+func (m RegistryMirrors) Mirror(image string) string {
+	matched, mirror := "", ""
+	for source, target := range m {
+		source = strings.TrimRight(source, "/")
+		if !strings.HasPrefix(image, source) || len(source) <= len(matched) {
+			continue
+		}
+		if rest := image[len(source):]; rest != "" && !strings.ContainsAny(rest[:1], "/:@") {
+			continue
+		}
+		matched, mirror = source, strings.TrimRight(target, "/")
+	}
+	if matched == "" {
+		return image
+	}
+	return mirror + image[len(matched):]
+}
+
+// SetMirroredImages replaces the images of the containers of the pod template with their registry mirror
+func SetMirroredImages(c client.Client, template *corev1.PodTemplateSpec) error {
+	mirrors, err := GetRegistryMirrors(c)
+	if err != nil {
+		return err
+	}
+	for i := range template.Spec.InitContainers {
+		template.Spec.InitContainers[i].Image = mirrors.Mirror(template.Spec.InitContainers[i].Image)
+	}
+	for i := range template.Spec.Containers {
+		template.Spec.Containers[i].Image = mirrors.Mirror(template.Spec.Containers[i].Image)
+	}
+	return nil
+}
+
+// IsRegistryMirrors returns whether the object is the registry mirrors configmap
+func IsRegistryMirrors(meta metav1.Object) bool {
+	return meta.GetName() == RegistryMirrorsConfigMapName && meta.GetNamespace() == operatorNamespace()
+}
+
+// operatorNamespace returns the namespace the operator runs in, or the namespace it watches
+// when it runs outside of the cluster
+func operatorNamespace() string {
+	if ns, err := k8sutil.GetOperatorNamespace(); err == nil {
+		return ns
+	}
+	return os.Getenv(k8sutil.WatchNamespaceEnvVar)
+}
@@ -0,0 +1,126 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+)
+
+// LegacyMustGatherResourceName is the name of the must gather service objects before it was
+// derived from the MustGatherService, it is still used by a MustGatherService which already manages them
+const LegacyMustGatherResourceName = "must-gather-service"
+
+// mustGatherResourceNameAnnotation records the name of the must gather service objects in the
+// MustGatherService when the spec doesn't set it, so that it doesn't change once they are created
+const mustGatherResourceNameAnnotation = "healthcheck.ibm.com/must-gather-service-name"
+
+// MustGatherResourceName returns the name of the must gather service statefulset, service and ingress.
+// It is spec.mustGather.name, else the name recorded by RecordMustGatherResourceName.
+func MustGatherResourceName(instance *operatorv1alpha1.MustGatherService) string {
+	if instance.Spec.MustGather.Name != "" {
+		return instance.Spec.MustGather.Name
+	}
+	if name := instance.Annotations[mustGatherResourceNameAnnotation]; name != "" {
+		return name
+	}
+	return instance.Name
+}
+
+// RecordMustGatherResourceName decides once the name of the must gather service objects when the spec
+// doesn't set it, and records it in an annotation of the MustGatherService. It is the legacy name if the
+// MustGatherService already manages the legacy statefulset, else the MustGatherService name.
+func RecordMustGatherResourceName(c client.Client, instance *operatorv1alpha1.MustGatherService) error {
+	if instance.Spec.MustGather.Name != "" || instance.Annotations[mustGatherResourceNameAnnotation] != "" {
+		return nil
+	}
+	name := instance.Name
+	sts := &appsv1.StatefulSet{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: LegacyMustGatherResourceName, Namespace: instance.Namespace}, sts)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && IsAdoptable(instance, sts) {
+		name = LegacyMustGatherResourceName
+	}
+
+	annotations := map[string]string{mustGatherResourceNameAnnotation: name}
+	for k, v := range instance.Annotations {
+		annotations[k] = v
+	}
+	instance.Annotations = annotations
+	return c.Update(context.TODO(), instance)
+}
+
+// ReferenceError is an invalid reference of a spec to a MustGatherService, it is reported in the
+// status of the custom resource until its spec is fixed
+type ReferenceError struct {
+	Reason string
+	Err    error
+}
+
+func (e *ReferenceError) Error() string {
+	return e.Err.Error()
+}
+
+// SelectMustGatherService returns the MustGatherService named name, else the only MustGatherService
+// of the namespace, nil if there is none. A missing named MustGatherService, or several MustGatherServices
+// without a name to select one, is a ReferenceError.
+func SelectMustGatherService(c client.Client, namespace, name string) (*operatorv1alpha1.MustGatherService, error) {
+	if name != "" {
+		mgs := &operatorv1alpha1.MustGatherService{}
+		err := c.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, mgs)
+		if errors.IsNotFound(err) {
+			return nil, &ReferenceError{"MustGatherServiceNotFound", fmt.Errorf("MustGatherService %s/%s not found", namespace, name)}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return mgs, nil
+	}
+
+	mgsList := &operatorv1alpha1.MustGatherServiceList{}
+	if err := c.List(context.TODO(), mgsList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	switch len(mgsList.Items) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &mgsList.Items[0], nil
+	}
+	return nil, &ReferenceError{"MustGatherServiceAmbiguous",
+		fmt.Errorf("%d MustGatherServices in namespace %s, set mustGatherServiceName to select one", len(mgsList.Items), namespace)}
+}
+
+// LabelsForMustGatherService returns the labels of the object name of the MustGatherService releaseName
+func LabelsForMustGatherService(name string, releaseName string) map[string]string {
+	return map[string]string{
+		"app":                          name,
+		"release":                      releaseName,
+		"app.kubernetes.io/name":       name,
+		"app.kubernetes.io/instance":   releaseName,
+		"app.kubernetes.io/managed-by": "",
+	}
+}
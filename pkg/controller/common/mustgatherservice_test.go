@@ -0,0 +1,95 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	goerrors "errors"
+	"testing"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSelectMustGatherService(t *testing.T) {
+	mgs := func(name, namespace string) runtime.Object {
+		return &operatorv1alpha1.MustGatherService{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	}
+	tests := []struct {
+		name    string
+		objs    []runtime.Object
+		ref     string
+		want    string
+		wantRef string
+	}{
+		{
+			name: "no MustGatherService",
+			objs: []runtime.Object{mgs("other", "other-ns")},
+		},
+		{
+			name: "only MustGatherService of the namespace",
+			objs: []runtime.Object{mgs("must-gather-service", "cs"), mgs("other", "other-ns")},
+			want: "must-gather-service",
+		},
+		{
+			name:    "several MustGatherServices",
+			objs:    []runtime.Object{mgs("a", "cs"), mgs("b", "cs")},
+			wantRef: "MustGatherServiceAmbiguous",
+		},
+		{
+			name: "named MustGatherService",
+			objs: []runtime.Object{mgs("a", "cs"), mgs("b", "cs")},
+			ref:  "b",
+			want: "b",
+		},
+		{
+			name:    "named MustGatherService not found",
+			objs:    []runtime.Object{mgs("a", "cs")},
+			ref:     "b",
+			wantRef: "MustGatherServiceNotFound",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := runtime.NewScheme()
+			if err := operatorv1alpha1.SchemeBuilder.AddToScheme(s); err != nil {
+				t.Fatal(err)
+			}
+			got, err := SelectMustGatherService(fake.NewFakeClientWithScheme(s, tt.objs...), "cs", tt.ref)
+			var refErr *ReferenceError
+			if tt.wantRef != "" {
+				if !goerrors.As(err, &refErr) || refErr.Reason != tt.wantRef {
+					t.Fatalf("SelectMustGatherService() error = %v, want reason %s", err, tt.wantRef)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectMustGatherService() error = %v", err)
+			}
+			name := ""
+			if got != nil {
+				name = got.Name
+			}
+			if name != tt.want {
+				t.Errorf("SelectMustGatherService() = %q, want %q", name, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IsAdoptable returns true if the object has no controller or is already controlled by owner
+func IsAdoptable(owner, obj metav1.Object) bool {
+	ref := metav1.GetControllerOf(obj)
+	return ref == nil || ref.UID == owner.GetUID()
+}
+
+// CheckOwnership returns an error if the object is controlled by another object than owner,
+// so that two custom resources don't overwrite the same child object
+func CheckOwnership(owner, obj metav1.Object) error {
+	if IsAdoptable(owner, obj) {
+		return nil
+	}
+	ref := metav1.GetControllerOf(obj)
+	return fmt.Errorf("%s/%s is already managed by %s %s", obj.GetNamespace(), obj.GetName(), ref.Kind, ref.Name)
+}
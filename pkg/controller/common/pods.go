@@ -0,0 +1,142 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+)
+
+// managedByLabel is set on all the operand pods
+const managedByLabel = "app.kubernetes.io/managed-by"
+
+// GetPodNames returns the names of the pods sorted by name
+func GetPodNames(pods []corev1.Pod) []string {
+	var podNames []string
+	for _, pod := range pods {
+		podNames = append(podNames, pod.Name)
+	}
+	sort.Strings(podNames)
+	return podNames
+}
+
+// PodStatuses returns the states of the pods sorted by name
+func PodStatuses(pods []corev1.Pod) []operatorv1alpha1.PodStatus {
+	statuses := []operatorv1alpha1.PodStatus{}
+	for i := range pods {
+		statuses = append(statuses, podStatus(&pods[i]))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func podStatus(pod *corev1.Pod) operatorv1alpha1.PodStatus {
+	status := operatorv1alpha1.PodStatus{
+		Name:     pod.Name,
+		Phase:    string(pod.Status.Phase),
+		NodeName: pod.Spec.NodeName,
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			status.Ready = c.Status == corev1.ConditionTrue
+		}
+	}
+
+	var lastTermination *corev1.ContainerStateTerminated
+	for _, cs := range pod.Status.ContainerStatuses {
+		status.RestartCount += cs.RestartCount
+		if t := cs.LastTerminationState.Terminated; t != nil {
+			if lastTermination == nil || lastTermination.FinishedAt.Before(&t.FinishedAt) {
+				lastTermination = t
+			}
+		}
+		if len(pod.Spec.Containers) > 0 && cs.Name == pod.Spec.Containers[0].Name {
+			status.ImageDigest = imageDigest(cs.ImageID)
+		}
+	}
+	if lastTermination != nil {
+		status.LastTerminationReason = lastTermination.Reason
+	}
+	return status
+}
+
+// imageDigest returns the digest of an image id such as docker-pullable://quay.io/repo@sha256:abc
+func imageDigest(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:]
+	}
+	return strings.TrimPrefix(imageID, "docker://")
+}
+
+// operandOwner is a ReplicaSet, Deployment, StatefulSet or Job between an operand pod and its custom resource
+type operandOwner interface {
+	runtime.Object
+	metav1.Object
+}
+
+// OperandPodRequests returns a mapper of an operand pod to the custom resource of the kind which controls it,
+// the controller owners of the pod are followed through its ReplicaSet, Deployment, StatefulSet or Job.
+// The pods which are not created by the operator are ignored.
+func OperandPodRequests(c client.Reader, kind string) handler.ToRequestsFunc {
+	return func(a handler.MapObject) []reconcile.Request {
+		if _, ok := a.Meta.GetLabels()[managedByLabel]; !ok {
+			return nil
+		}
+		owner := metav1.GetControllerOf(a.Meta)
+		// pod, ReplicaSet, Deployment, custom resource is the longest chain
+		for i := 0; owner != nil && i < 3; i++ {
+			if owner.Kind == kind && owner.APIVersion == operatorv1alpha1.SchemeGroupVersion.String() {
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{
+					Namespace: a.Meta.GetNamespace(),
+					Name:      owner.Name,
+				}}}
+			}
+			var obj operandOwner
+			switch owner.Kind {
+			case "ReplicaSet":
+				obj = &appsv1.ReplicaSet{}
+			case "Deployment":
+				obj = &appsv1.Deployment{}
+			case "StatefulSet":
+				obj = &appsv1.StatefulSet{}
+			case "Job":
+				obj = &batchv1.Job{}
+			default:
+				return nil
+			}
+			key := types.NamespacedName{Namespace: a.Meta.GetNamespace(), Name: owner.Name}
+			if err := c.Get(context.TODO(), key, obj); err != nil {
+				return nil
+			}
+			owner = metav1.GetControllerOf(obj)
+		}
+		return nil
+	}
+}
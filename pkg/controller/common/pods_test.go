@@ -0,0 +1,104 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func controllerRef(apiVersion, kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{APIVersion: apiVersion, Kind: kind, Name: name, UID: types.UID(name), Controller: &controller}}
+}
+
+func operandMeta(name string, owners []metav1.OwnerReference) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       "ibm-common-services",
+		Labels:          map[string]string{managedByLabel: "", "release": "must-gather-service"},
+		OwnerReferences: owners,
+	}
+}
+
+func TestOperandPodRequests(t *testing.T) {
+	operator := operatorv1alpha1.SchemeGroupVersion.String()
+	objs := []runtime.Object{
+		&appsv1.Deployment{ObjectMeta: operandMeta("system-healthcheck-service",
+			controllerRef(operator, "HealthService", "system-healthcheck-service"))},
+		&appsv1.ReplicaSet{ObjectMeta: operandMeta("system-healthcheck-service-5d8f",
+			controllerRef("apps/v1", "Deployment", "system-healthcheck-service"))},
+		&appsv1.StatefulSet{ObjectMeta: operandMeta("must-gather-service",
+			controllerRef(operator, "MustGatherService", "must-gather-service"))},
+		&batchv1.Job{ObjectMeta: operandMeta("must-gather-service",
+			controllerRef(operator, "MustGatherJob", "must-gather-service"))},
+	}
+	s := runtime.NewScheme()
+	if err := appsv1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := batchv1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewFakeClientWithScheme(s, objs...)
+
+	request := func(name string) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "ibm-common-services", Name: name}}}
+	}
+	unmanaged := operandMeta("other", controllerRef("apps/v1", "StatefulSet", "must-gather-service"))
+	delete(unmanaged.Labels, managedByLabel)
+
+	tests := []struct {
+		name string
+		pod  metav1.ObjectMeta
+		kind string
+		want []reconcile.Request
+	}{
+		{"Deployment pod", operandMeta("hs-1", controllerRef("apps/v1", "ReplicaSet", "system-healthcheck-service-5d8f")),
+			"HealthService", request("system-healthcheck-service")},
+		{"StatefulSet pod", operandMeta("mgs-0", controllerRef("apps/v1", "StatefulSet", "must-gather-service")),
+			"MustGatherService", request("must-gather-service")},
+		{"Job pod", operandMeta("mgj-1", controllerRef("batch/v1", "Job", "must-gather-service")),
+			"MustGatherJob", request("must-gather-service")},
+		{"Job pod of another kind", operandMeta("mgj-1", controllerRef("batch/v1", "Job", "must-gather-service")),
+			"MustGatherService", nil},
+		{"missing owner", operandMeta("mgs-0", controllerRef("apps/v1", "StatefulSet", "deleted")),
+			"MustGatherService", nil},
+		{"not managed by the operator", unmanaged, "MustGatherService", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: tt.pod}
+			got := OperandPodRequests(c, tt.kind)(handler.MapObject{Meta: pod, Object: pod})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("requests = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// MergeTolerations returns the default tolerations followed by the custom ones.
+// A custom toleration replaces the default toleration with the same key and effect.
+func MergeTolerations(defaults, custom []corev1.Toleration) []corev1.Toleration {
+	merged := []corev1.Toleration{}
+	for _, d := range defaults {
+		overridden := false
+		for _, c := range custom {
+			if c.Key == d.Key && c.Effect == d.Effect {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, d)
+		}
+	}
+	for _, c := range custom {
+		merged = append(merged, *c.DeepCopy())
+	}
+	return merged
+}
+
+// MergeSecurityContext returns a copy of the default security context in which every field
+// set in the custom security context overrides the default value.
+func MergeSecurityContext(defaults, custom *corev1.SecurityContext) *corev1.SecurityContext {
+	merged := defaults.DeepCopy()
+	if custom == nil {
+		return merged
+	}
+	c := custom.DeepCopy()
+	if c.Capabilities != nil {
+		merged.Capabilities = c.Capabilities
+	}
+	if c.Privileged != nil {
+		merged.Privileged = c.Privileged
+	}
+	if c.SELinuxOptions != nil {
+		merged.SELinuxOptions = c.SELinuxOptions
+	}
+	if c.WindowsOptions != nil {
+		merged.WindowsOptions = c.WindowsOptions
+	}
+	if c.RunAsUser != nil {
+		merged.RunAsUser = c.RunAsUser
+	}
+	if c.RunAsGroup != nil {
+		merged.RunAsGroup = c.RunAsGroup
+	}
+	if c.RunAsNonRoot != nil {
+		merged.RunAsNonRoot = c.RunAsNonRoot
+	}
+	if c.ReadOnlyRootFilesystem != nil {
+		merged.ReadOnlyRootFilesystem = c.ReadOnlyRootFilesystem
+	}
+	if c.AllowPrivilegeEscalation != nil {
+		merged.AllowPrivilegeEscalation = c.AllowPrivilegeEscalation
+	}
+	if c.ProcMount != nil {
+		merged.ProcMount = c.ProcMount
+	}
+	if c.SeccompProfile != nil {
+		merged.SeccompProfile = c.SeccompProfile
+	}
+	return merged
+}
+
+// DNSPolicy returns the dns policy a pod needs to resolve cluster services
+func DNSPolicy(hostNetwork bool) corev1.DNSPolicy {
+	if hostNetwork {
+		return corev1.DNSClusterFirstWithHostNet
+	}
+	return corev1.DNSClusterFirst
+}
@@ -0,0 +1,140 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DeploymentRolloutStatus reports whether the rollout of a deployment is complete, the same way
+// "kubectl rollout status" does. failed is true when the deployment exceeded its progress deadline.
+func DeploymentRolloutStatus(dep *appsv1.Deployment) (done bool, failed bool, message string) {
+	if dep.Generation > dep.Status.ObservedGeneration {
+		return false, false, fmt.Sprintf("waiting for deployment %q spec update to be observed", dep.Name)
+	}
+
+	for _, cond := range dep.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+			return false, true, fmt.Sprintf("deployment %q exceeded its progress deadline", dep.Name)
+		}
+	}
+
+	replicas := int32(1)
+	if dep.Spec.Replicas != nil {
+		replicas = *dep.Spec.Replicas
+	}
+	if dep.Status.UpdatedReplicas < replicas {
+		return false, false, fmt.Sprintf("deployment %q: %d out of %d new replicas have been updated",
+			dep.Name, dep.Status.UpdatedReplicas, replicas)
+	}
+	if dep.Status.Replicas > dep.Status.UpdatedReplicas {
+		return false, false, fmt.Sprintf("deployment %q: %d old replicas are pending termination",
+			dep.Name, dep.Status.Replicas-dep.Status.UpdatedReplicas)
+	}
+	if dep.Status.AvailableReplicas < dep.Status.UpdatedReplicas {
+		return false, false, fmt.Sprintf("deployment %q: %d of %d updated replicas are available",
+			dep.Name, dep.Status.AvailableReplicas, dep.Status.UpdatedReplicas)
+	}
+
+	return true, false, fmt.Sprintf("deployment %q successfully rolled out", dep.Name)
+}
+
+// EndpointsReady returns true if the endpoints have at least one ready address
+func EndpointsReady(ep *corev1.Endpoints) bool {
+	for _, subset := range ep.Subsets {
+		if len(subset.Addresses) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// PatchStatus writes the status of obj, gathered in memory during the reconcile, with a single merge
+// patch from original. The patch carries the resourceVersion of original, so that a concurrent write
+// is detected: on a conflict the latest object is read into original with reader, which reads from the
+// apiserver as the cache may still hold the conflicting version, setStatus sets the status gathered in
+// memory on obj again, and the patch is retried.
+func PatchStatus(c client.Client, reader client.Reader, original, obj runtime.Object, setStatus func()) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		patch, err := statusPatch(original, obj)
+		if err != nil || patch == nil {
+			return err
+		}
+		err = c.Status().Patch(context.TODO(), obj, patch)
+		if !errors.IsConflict(err) {
+			return err
+		}
+
+		key, keyErr := client.ObjectKeyFromObject(obj)
+		if keyErr != nil {
+			return keyErr
+		}
+		// read into an empty object, so that the fields cleared since original was read are not kept
+		latest := reflect.New(reflect.TypeOf(original).Elem()).Interface().(runtime.Object)
+		if getErr := reader.Get(context.TODO(), key, latest); getErr != nil {
+			return getErr
+		}
+		reflect.ValueOf(original).Elem().Set(reflect.ValueOf(latest).Elem())
+		setStatus()
+		return err
+	})
+}
+
+// statusPatch returns the merge patch from original to obj guarded by the resourceVersion of original,
+// nil if obj didn't change
+func statusPatch(original, obj runtime.Object) (client.Patch, error) {
+	data, err := client.MergeFrom(original).Data(obj)
+	if err != nil {
+		return nil, err
+	}
+	patch := map[string]interface{}{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+	if len(patch) == 0 {
+		return nil, nil
+	}
+
+	accessor, err := meta.Accessor(original)
+	if err != nil {
+		return nil, err
+	}
+	metadata, ok := patch["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = map[string]interface{}{}
+	}
+	metadata["resourceVersion"] = accessor.GetResourceVersion()
+	patch["metadata"] = metadata
+
+	data, err = json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	return client.RawPatch(types.MergePatchType, data), nil
+}
@@ -0,0 +1,111 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"context"
+	goerrors "errors"
+	"testing"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// staleCacheClient fails the test when it is read from, and fails the first status patches with a conflict
+type staleCacheClient struct {
+	client.Client
+	t         *testing.T
+	conflicts int
+}
+
+func (c *staleCacheClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	c.t.Errorf("Get(%s) from the cache", key)
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c *staleCacheClient) Status() client.StatusWriter {
+	return &conflictStatusWriter{StatusWriter: c.Client.Status(), c: c}
+}
+
+type conflictStatusWriter struct {
+	client.StatusWriter
+	c *staleCacheClient
+}
+
+func (w *conflictStatusWriter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if w.c.conflicts > 0 {
+		w.c.conflicts--
+		return errors.NewConflict(schema.GroupResource{Group: "operator.ibm.com", Resource: "mustgatherconfigs"}, "config",
+			goerrors.New("the object has been modified"))
+	}
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+func TestPatchStatusConflict(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := operatorv1alpha1.SchemeBuilder.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	stored := &operatorv1alpha1.MustGatherConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "ibm-common-services"},
+		Status: operatorv1alpha1.MustGatherConfigStatus{
+			Conditions: []metav1.Condition{{Type: "Stale", Status: metav1.ConditionTrue, Reason: "Stale"}},
+		},
+	}
+	apiserver := fake.NewFakeClientWithScheme(s, stored)
+	c := &staleCacheClient{Client: apiserver, t: t, conflicts: 1}
+
+	original := &operatorv1alpha1.MustGatherConfig{}
+	if err := apiserver.Get(context.TODO(), client.ObjectKey{Namespace: "ibm-common-services", Name: "config"}, original); err != nil {
+		t.Fatal(err)
+	}
+	// a concurrent write cleared the conditions since original was read
+	latest := original.DeepCopy()
+	latest.Status.Conditions = nil
+	if err := apiserver.Status().Update(context.TODO(), latest); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := metav1.Condition{Type: operatorv1alpha1.ConditionValid, Status: metav1.ConditionTrue, Reason: "Valid"}
+	instance := original.DeepCopy()
+	meta.SetStatusCondition(&instance.Status.Conditions, valid)
+	setStatus := func() {
+		original.DeepCopyInto(instance)
+		meta.SetStatusCondition(&instance.Status.Conditions, valid)
+	}
+	if err := PatchStatus(c, apiserver, original, instance, setStatus); err != nil {
+		t.Fatal(err)
+	}
+
+	got := &operatorv1alpha1.MustGatherConfig{}
+	if err := apiserver.Get(context.TODO(), client.ObjectKey{Namespace: "ibm-common-services", Name: "config"}, got); err != nil {
+		t.Fatal(err)
+	}
+	if meta.FindStatusCondition(got.Status.Conditions, "Stale") != nil {
+		t.Errorf("conditions = %v, the cleared Stale condition is written back", got.Status.Conditions)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, operatorv1alpha1.ConditionValid) {
+		t.Errorf("conditions = %v, want Valid", got.Status.Conditions)
+	}
+}
@@ -0,0 +1,76 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// CreateOrUpdate creates desired if it doesn't exist, or else reads it into current. An existing object
+// must be adoptable by owner. update returns a copy of current in which the desired fields are set,
+// and the copy is written only if it drifted from current, see IsDrifted.
+func CreateOrUpdate(c client.Client, owner metav1.Object, desired, current runtime.Object,
+	update func() runtime.Object) (controllerutil.OperationResult, error) {
+	key, err := client.ObjectKeyFromObject(desired)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	if err := c.Get(context.TODO(), key, current); err != nil {
+		if !errors.IsNotFound(err) {
+			return controllerutil.OperationResultNone, err
+		}
+		if err := c.Create(context.TODO(), desired); err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+		return controllerutil.OperationResultCreated, nil
+	}
+
+	currentMeta, err := meta.Accessor(current)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	if err := CheckOwnership(owner, currentMeta); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	updated := update()
+	if !IsDrifted(current, updated) {
+		return controllerutil.OperationResultNone, nil
+	}
+	if err := c.Update(context.TODO(), updated); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	return controllerutil.OperationResultUpdated, nil
+}
+
+// IsDrifted returns true if updated, a copy of current in which the desired fields are set, differs from
+// current. The fields the API server defaults are set to their default in copies of both objects first,
+// so that a desired object which leaves them unset doesn't drift from the stored object.
+func IsDrifted(current, updated runtime.Object) bool {
+	c, u := current.DeepCopyObject(), updated.DeepCopyObject()
+	setServerDefaults(c)
+	setServerDefaults(u)
+	return !equality.Semantic.DeepEqual(c, u)
+}
@@ -0,0 +1,212 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// storedDeployment is a deployment as read from the API server, with the defaults it sets
+func storedDeployment() *appsv1.Deployment {
+	mode := int32(0644)
+	replicas := int32(1)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "system-healthcheck-service",
+			Namespace:         "ibm-common-services",
+			CreationTimestamp: metav1.NewTime(time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)),
+			ResourceVersion:   "42",
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					NodeSelector: map[string]string{"node-role.kubernetes.io/worker": ""},
+					Containers: []corev1.Container{{
+						Name:                     "system-healthcheck-service",
+						Image:                    "icr.io/cpopen/cpfs/system-healthcheck-service:3.24.12",
+						ImagePullPolicy:          corev1.PullIfNotPresent,
+						TerminationMessagePath:   corev1.TerminationMessagePathDefault,
+						TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+						Ports:                    []corev1.ContainerPort{{ContainerPort: 6967, Protocol: corev1.ProtocolTCP}},
+						Resources: corev1.ResourceRequirements{
+							Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+						},
+						LivenessProbe: &corev1.Probe{
+							Handler: corev1.Handler{HTTPGet: &corev1.HTTPGetAction{
+								Path: "/healthz", Port: intstr.FromInt(6967), Scheme: corev1.URISchemeHTTP,
+							}},
+							TimeoutSeconds:   1,
+							PeriodSeconds:    10,
+							SuccessThreshold: 1,
+							FailureThreshold: 3,
+						},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "cpnames",
+						VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "system-healthcheck-service-config"},
+							DefaultMode:          &mode,
+						}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestIsDrifted(t *testing.T) {
+	tests := []struct {
+		name    string
+		current runtime.Object
+		update  func(current runtime.Object) runtime.Object
+		drifted bool
+	}{
+		{
+			name:    "identical deployment",
+			current: storedDeployment(),
+			update:  func(current runtime.Object) runtime.Object { return current.DeepCopyObject() },
+		},
+		{
+			name:    "server defaults unset in the desired containers and volumes",
+			current: storedDeployment(),
+			update: func(current runtime.Object) runtime.Object {
+				updated := current.DeepCopyObject().(*appsv1.Deployment)
+				c := &updated.Spec.Template.Spec.Containers[0]
+				c.ImagePullPolicy = ""
+				c.TerminationMessagePath = ""
+				c.TerminationMessagePolicy = ""
+				c.Ports[0].Protocol = ""
+				c.LivenessProbe.TimeoutSeconds = 0
+				c.LivenessProbe.PeriodSeconds = 0
+				c.LivenessProbe.SuccessThreshold = 0
+				c.LivenessProbe.FailureThreshold = 0
+				c.LivenessProbe.HTTPGet.Scheme = ""
+				c.Resources.Limits[corev1.ResourceCPU] = resource.MustParse("1000m")
+				updated.Spec.Template.Spec.Volumes[0].ConfigMap.DefaultMode = nil
+				return updated
+			},
+		},
+		{
+			name:    "node selector removed",
+			current: storedDeployment(),
+			update: func(current runtime.Object) runtime.Object {
+				updated := current.DeepCopyObject().(*appsv1.Deployment)
+				updated.Spec.Template.Spec.NodeSelector = nil
+				return updated
+			},
+			drifted: true,
+		},
+		{
+			name:    "limits removed",
+			current: storedDeployment(),
+			update: func(current runtime.Object) runtime.Object {
+				updated := current.DeepCopyObject().(*appsv1.Deployment)
+				updated.Spec.Template.Spec.Containers[0].Resources.Limits = nil
+				return updated
+			},
+			drifted: true,
+		},
+		{
+			name:    "image changed",
+			current: storedDeployment(),
+			update: func(current runtime.Object) runtime.Object {
+				updated := current.DeepCopyObject().(*appsv1.Deployment)
+				updated.Spec.Template.Spec.Containers[0].Image = "mirror.local/cpopen/cpfs/system-healthcheck-service:3.24.12"
+				return updated
+			},
+			drifted: true,
+		},
+		{
+			name:    "pull policy changed from the default",
+			current: storedDeployment(),
+			update: func(current runtime.Object) runtime.Object {
+				updated := current.DeepCopyObject().(*appsv1.Deployment)
+				updated.Spec.Template.Spec.Containers[0].ImagePullPolicy = corev1.PullAlways
+				return updated
+			},
+			drifted: true,
+		},
+		{
+			name: "service port defaults",
+			current: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "svc", CreationTimestamp: metav1.Now()},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Port: 6967, TargetPort: intstr.FromInt(6967), Protocol: corev1.ProtocolTCP}},
+				},
+			},
+			update: func(current runtime.Object) runtime.Object {
+				updated := current.DeepCopyObject().(*corev1.Service)
+				updated.Spec.Ports = []corev1.ServicePort{{Port: 6967}}
+				return updated
+			},
+		},
+		{
+			name: "ingress tls, class and annotations removed",
+			current: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "ingress",
+					CreationTimestamp: metav1.Now(),
+					Annotations:       map[string]string{"icp.management.ibm.com/rewrite-target": "/"},
+				},
+				Spec: networkingv1.IngressSpec{
+					IngressClassName: func() *string { s := "nginx"; return &s }(),
+					TLS:              []networkingv1.IngressTLS{{SecretName: "tls"}},
+				},
+			},
+			update: func(current runtime.Object) runtime.Object {
+				updated := current.DeepCopyObject().(*networkingv1.Ingress)
+				updated.Annotations = nil
+				updated.Spec.IngressClassName = nil
+				updated.Spec.TLS = nil
+				return updated
+			},
+			drifted: true,
+		},
+		{
+			name: "configmap data removed",
+			current: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "cm", CreationTimestamp: metav1.Now()},
+				Data:       map[string]string{"gather_config": "x", "redaction.json": "{}"},
+			},
+			update: func(current runtime.Object) runtime.Object {
+				updated := current.DeepCopyObject().(*corev1.ConfigMap)
+				delete(updated.Data, "redaction.json")
+				return updated
+			},
+			drifted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := tt.update(tt.current)
+			if got := IsDrifted(tt.current, updated); got != tt.drifted {
+				t.Errorf("IsDrifted() = %v, want %v", got, tt.drifted)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package constant
+
+// The images of the operands, the image of an operand is the image set in its custom resource,
+// else the image of its operator env var, else its default image
+const (
+	HealthServiceImageEnv     = "SYSTEM_HEALTHCHECK_SERVICE_IMAGE"
+	MemcachedImageEnv         = "ICP_MEMCACHED_IMAGE"
+	MustGatherImageEnv        = "MUST_GATHER_IMAGE"
+	MustGatherServiceImageEnv = "MUST_GATHER_SERVICE_IMAGE"
+
+	DefaultHealthServiceImage     = "icr.io/cpopen/cpfs/system-healthcheck-service:3.10.23"
+	DefaultMemcachedImage         = "icr.io/cpopen/cpfs/icp-memcached:3.10.23"
+	DefaultMustGatherImage        = "icr.io/cpopen/cpfs/must-gather:4.6.24"
+	DefaultMustGatherServiceImage = "icr.io/cpopen/cpfs/must-gather-service:1.3.23"
+)
+
+// OperatorImageEnv is the env var of the image of the operator, which redacts the gathered data in the
+// must gather jobs, default is the image of the operator pod
+const OperatorImageEnv = "OPERATOR_IMAGE"
@@ -0,0 +1,203 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package healthservice
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	clusterhealthv1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/clusterhealth/v1"
+	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// cpNamesManifest is the health service configmap shipped with the operator
+var cpNamesManifest = "/manifests/system-healthcheck-service-config.yaml"
+
+// cpNamesKey is the configmap key mounted at CPNAMESCONFIGPATH
+const cpNamesKey = "cpnames.yaml"
+
+// managedCPNamesAnnotation lists the ids of the cpnames written by the operator, so the cpnames of
+// deleted CloudPaks can be told apart from the cpnames added by users
+const managedCPNamesAnnotation = "healthcheck.ibm.com/managed-cpnames"
+
+// cloudPakGroupKind is the kind of the CloudPaks aggregated into cpnames.yaml
+var cloudPakGroupKind = schema.GroupKind{Group: clusterhealthv1.SchemeGroupVersion.Group, Kind: "CloudPak"}
+
+// cpName identifies a CloudPak
+type cpName struct {
+	FullName  string `json:"fullname"`
+	ShortName string `json:"shortname"`
+	ID        string `json:"id"`
+}
+
+// cpNames is the content of cpnames.yaml
+type cpNames struct {
+	CPNames []cpName `json:"cpnames"`
+}
+
+// configError is an error of the configuration shipped with the operator or of the health service configmap.
+// It is reported with the ConfigValid condition.
+type configError struct {
+	reason string
+	err    error
+}
+
+func (e *configError) Error() string {
+	return e.err.Error()
+}
+
+// readCPNamesManifest returns the cpnames shipped with the operator
+func readCPNamesManifest() (*corev1.ConfigMap, []cpName, error) {
+	data, err := ioutil.ReadFile(cpNamesManifest)
+	if err != nil {
+		return nil, nil, &configError{"ManifestInvalid", fmt.Errorf("failed to read %s: %v", cpNamesManifest, err)}
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := yaml.Unmarshal(data, cm); err != nil {
+		return nil, nil, &configError{"ManifestInvalid", fmt.Errorf("failed to parse %s: %v", cpNamesManifest, err)}
+	}
+	names, err := parseCPNames(cm.Data[cpNamesKey])
+	if err != nil {
+		return nil, nil, &configError{"ManifestInvalid", fmt.Errorf("failed to parse %s of %s: %v", cpNamesKey, cpNamesManifest, err)}
+	}
+	if len(names) == 0 {
+		return nil, nil, &configError{"ManifestInvalid", fmt.Errorf("%s of %s has no cpnames", cpNamesKey, cpNamesManifest)}
+	}
+	return cm, names, nil
+}
+
+func parseCPNames(data string) ([]cpName, error) {
+	names := &cpNames{}
+	if err := yaml.UnmarshalStrict([]byte(data), names); err != nil {
+		return nil, err
+	}
+	return names.CPNames, nil
+}
+
+func marshalCPNames(names []cpName) (string, error) {
+	data, err := yaml.Marshal(&cpNames{CPNames: names})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// cloudPakNames returns the cpnames shipped with the operator merged with the CloudPaks of the cluster.
+// A CloudPak overrides the shipped cpname of the same id. The CloudPaks are taken in name order, one
+// which reuses the id or the short name of a previous CloudPak, or the short name of another shipped
+// cpname, is skipped and reported with a configError.
+func (r *ReconcileHealthService) cloudPakNames(operatorNames []cpName) ([]cpName, error) {
+	if _, ok := common.ServedKind(r.mapper, cloudPakGroupKind); !ok {
+		return operatorNames, nil
+	}
+	cloudPakList := &clusterhealthv1.CloudPakList{}
+	if err := r.client.List(context.TODO(), cloudPakList); err != nil {
+		return nil, err
+	}
+	cloudPaks := cloudPakList.Items
+	sort.Slice(cloudPaks, func(i, j int) bool { return cloudPaks[i].Name < cloudPaks[j].Name })
+
+	names := append([]cpName{}, operatorNames...)
+	index := map[string]int{}
+	for i, n := range names {
+		index[n.ID] = i
+	}
+	fromCloudPak := map[string]string{}
+	conflicts := []string{}
+	for _, cp := range cloudPaks {
+		n := cpName{FullName: cp.Spec.FullName, ShortName: cp.Spec.ShortName, ID: cp.Spec.ID}
+		if owner, ok := fromCloudPak[n.ID]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("CloudPak %s: id %s is already used by CloudPak %s", cp.Name, n.ID, owner))
+			continue
+		}
+		if conflict := shortNameConflict(names, n); conflict != "" {
+			conflicts = append(conflicts, fmt.Sprintf("CloudPak %s: short name %s is already used by id %s", cp.Name, n.ShortName, conflict))
+			continue
+		}
+		fromCloudPak[n.ID] = cp.Name
+		if i, ok := index[n.ID]; ok {
+			names[i] = n
+			continue
+		}
+		index[n.ID] = len(names)
+		names = append(names, n)
+	}
+
+	if len(conflicts) > 0 {
+		return names, &configError{"DuplicateCloudPak", fmt.Errorf("duplicate CloudPaks: %s", strings.Join(conflicts, "; "))}
+	}
+	return names, nil
+}
+
+// shortNameConflict returns the id of another cpname with the short name of n
+func shortNameConflict(names []cpName, n cpName) string {
+	for _, other := range names {
+		if other.ShortName == n.ShortName && other.ID != n.ID {
+			return other.ID
+		}
+	}
+	return ""
+}
+
+// managedCPNames returns the ids listed in the managedCPNamesAnnotation of the configmap
+func managedCPNames(cm *corev1.ConfigMap) map[string]bool {
+	ids := map[string]bool{}
+	for _, id := range strings.Split(cm.Annotations[managedCPNamesAnnotation], ",") {
+		if id != "" {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// managedCPNamesValue returns the value of the managedCPNamesAnnotation for the cpnames of the operator
+func managedCPNamesValue(names []cpName) string {
+	ids := []string{}
+	for _, n := range names {
+		ids = append(ids, n.ID)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+// mergeCPNames returns the operator cpnames followed by the user cpnames which have another id.
+// The operator entries are refreshed on upgrade, the entries added by users are kept. The user
+// entries with a previously managed id belong to a CloudPak which was deleted, and are dropped.
+func mergeCPNames(operator, user []cpName, previouslyManaged map[string]bool) []cpName {
+	merged := []cpName{}
+	ids := map[string]bool{}
+	for _, n := range operator {
+		merged = append(merged, n)
+		ids[n.ID] = true
+	}
+	for _, n := range user {
+		if ids[n.ID] || previouslyManaged[n.ID] {
+			continue
+		}
+		merged = append(merged, n)
+		ids[n.ID] = true
+	}
+	return merged
+}
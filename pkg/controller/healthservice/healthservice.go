@@ -18,8 +18,7 @@ package healthservice
 
 import (
 	"context"
-	"io/ioutil"
-	"os"
+	"fmt"
 	"reflect"
 
 	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
@@ -31,42 +30,46 @@ import (
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
-	"sigs.k8s.io/yaml"
 )
 
 var gracePeriod = int64(60)
 var mode484 = int32(484)
 
-var healthResourceName = "system-healthcheck-service"
-
 func (r *ReconcileHealthService) createOrUpdateHealthServiceDeploy(h *operatorv1alpha1.HealthService) error {
-	hsName := healthResourceName
+	hsName := healthResourceName(h)
 	reqLogger := log.WithValues("HealthService.Namespace", h.Namespace, "HealthService.Name", h.Name)
 
 	// Define a new deployment
 	desired := r.desiredHealthServiceDeployment(h)
-	// Check if the deployment already exists, if not create a new one
-	current := &appsv1.Deployment{}
-	err := r.client.Get(context.TODO(), types.NamespacedName{Name: hsName, Namespace: h.Namespace}, current)
-
-	if err != nil && errors.IsNotFound(err) {
-		reqLogger.Info("Creating a new Deployment", "Deployment.Namespace", desired.Namespace, "Deployment.Name", desired.Name)
-		if err := r.client.Create(context.TODO(), desired); err != nil {
-			reqLogger.Error(err, "Failed to create new Deployment", "Deployment.Namespace", desired.Namespace, "Deployment.Name", desired.Name)
-			return err
-		}
-	} else if err != nil {
-		reqLogger.Error(err, "Failed to get Deployment", "Deployment.Namespace", current.Namespace, "Deployment.Name", current.Name)
+	// Pull the images from their registry mirror
+	if err := common.SetMirroredImages(r.client, &desired.Spec.Template); err != nil {
+		reqLogger.Error(err, "Failed to get the registry mirrors", "Deployment.Namespace", desired.Namespace, "Deployment.Name", desired.Name)
+		return err
+	}
+	// Roll the pods out when the cpnames change
+	if err := common.SetConfigHash(r.client, h.Namespace, &desired.Spec.Template); err != nil {
+		reqLogger.Error(err, "Failed to hash the mounted configmaps", "Deployment.Namespace", desired.Namespace, "Deployment.Name", desired.Name)
 		return err
-	} else if err := r.updateHealthServiceDeployment(h, current, desired); err != nil {
+	}
+	// Create the deployment, or update it if it drifted from the desired state
+	current := &appsv1.Deployment{}
+	result, err := common.CreateOrUpdate(r.client, h, desired, current, func() runtime.Object {
+		return r.updatedHealthServiceDeployment(h, current, desired)
+	})
+	if err != nil {
+		reqLogger.Error(err, "Failed to create or update Deployment", "Deployment.Namespace", desired.Namespace, "Deployment.Name", desired.Name)
 		return err
 	}
+	if result != controllerutil.OperationResultNone {
+		reqLogger.Info("Deployment "+string(result), "Deployment.Namespace", desired.Namespace, "Deployment.Name", desired.Name)
+	}
 
-	// Update the HealthService status with the pod names
+	// Record the pod states in the HealthService status
 	podList := &corev1.PodList{}
 	listOpts := []client.ListOption{
 		client.InNamespace(h.Namespace),
@@ -76,40 +79,68 @@ func (r *ReconcileHealthService) createOrUpdateHealthServiceDeploy(h *operatorv1
 		reqLogger.Error(err, "Failed to list pods", "h.Namespace", h.Namespace, "h.Name", hsName)
 		return err
 	}
-	podNames := common.GetPodNames(podList.Items)
-
-	// Update status.HealthCheckNodes if needed
-	if !reflect.DeepEqual(podNames, h.Status.HealthCheckNodes) {
-		h.Status.HealthCheckNodes = podNames
-		err := r.client.Status().Update(context.TODO(), h)
-		if err != nil {
-			reqLogger.Error(err, "Failed to update HealthService status")
-			return err
-		}
-	}
+	// The status is written once at the end of the reconcile
+	h.Status.HealthCheckNodes = common.GetPodNames(podList.Items)
+	h.Status.HealthCheckPods = common.PodStatuses(podList.Items)
+	h.Status.HealthCheckImage = common.RunningImage(desired.Spec.Template.Spec.Containers[0].Image, podList.Items)
 
 	return nil
 }
 
 func (r *ReconcileHealthService) createOrUpdateHealthServiceService(h *operatorv1alpha1.HealthService) error {
-	hsName := healthResourceName
 	reqLogger := log.WithValues("HealthService.Namespace", h.Namespace, "HealthService.Name", h.Name)
 
 	// Define a new service
 	desired := r.desiredHealthServiceService(h)
-	// Check if the service already exists, if not create a new one
+	// Create the service, or update it if it drifted from the desired state
 	current := &corev1.Service{}
-	err := r.client.Get(context.TODO(), types.NamespacedName{Name: hsName, Namespace: h.Namespace}, current)
-	if err != nil && errors.IsNotFound(err) {
-		reqLogger.Info("Creating a new Service", "Service.Namespace", desired.Namespace, "Service.Name", desired.Name)
-		if err := r.client.Create(context.TODO(), desired); err != nil {
-			reqLogger.Error(err, "Failed to create new Service", "Service.Namespace", desired.Namespace, "Service.Name", desired.Name)
+	result, err := common.CreateOrUpdate(r.client, h, desired, current, func() runtime.Object {
+		return r.updatedHealthServiceService(h, current, desired)
+	})
+	if err != nil {
+		reqLogger.Error(err, "Failed to create or update Service", "Service.Namespace", desired.Namespace, "Service.Name", desired.Name)
+		return err
+	}
+	if result != controllerutil.OperationResultNone {
+		reqLogger.Info("Service "+string(result), "Service.Namespace", desired.Namespace, "Service.Name", desired.Name)
+	}
+
+	return nil
+}
+
+// createOrUpdateHealthServiceExposure exposes the health service with an Ingress, a Route or an HTTPRoute
+// depending on the exposure mode, and deletes the objects of the other modes
+func (r *ReconcileHealthService) createOrUpdateHealthServiceExposure(h *operatorv1alpha1.HealthService) error {
+	name := healthResourceName(h)
+	reqLogger := log.WithValues("HealthService.Namespace", h.Namespace, "HealthService.Name", h.Name)
+
+	mode, err := common.ResolveExposureMode(r.mapper, h.Spec.Exposure.Mode)
+	if err != nil {
+		reqLogger.Error(err, "Failed to resolve exposure mode")
+		return err
+	}
+
+	if mode == operatorv1alpha1.ExposureIngress {
+		if err := r.createOrUpdateHealthServiceIngress(h); err != nil {
 			return err
 		}
-	} else if err != nil {
-		reqLogger.Error(err, "Failed to get Service", "Service.Namespace", current.Namespace, "Service.Name", current.Name)
+	} else if err := r.deleteHealthServiceIngress(h); err != nil {
 		return err
-	} else if err := r.updateHealthServiceService(h, current, desired); err != nil {
+	}
+
+	target := common.ExposureTarget{
+		Name:      name,
+		Namespace: h.Namespace,
+		Labels:    labelsForHealthService(name, h.Name),
+		Path:      constant.HealthServiceRoute,
+		Port:      constant.HealthServicePort,
+	}
+	if h.Spec.Ingress.Path != "" {
+		target.Path = h.Spec.Ingress.Path
+	}
+
+	if err := common.ReconcileExposure(r.client, r.mapper, r.scheme, h, mode, &h.Spec.Exposure, target); err != nil {
+		reqLogger.Error(err, "Failed to reconcile the Route or HTTPRoute")
 		return err
 	}
 
@@ -117,62 +148,74 @@ func (r *ReconcileHealthService) createOrUpdateHealthServiceService(h *operatorv
 }
 
 func (r *ReconcileHealthService) createOrUpdateHealthServiceIngress(h *operatorv1alpha1.HealthService) error {
-	hsName := healthResourceName
 	reqLogger := log.WithValues("HealthService.Namespace", h.Namespace, "HealthService.Name", h.Name)
 
+	if h.Spec.Ingress.Disabled {
+		return r.deleteHealthServiceIngress(h)
+	}
+
 	// Define a new ingress
 	desired := r.desiredHealthServiceIngress(h)
-	// Check if the ingress already exists, if not create a new one
+	// Create the ingress, or update it if it drifted from the desired state
 	current := &networkingv1.Ingress{}
-	err := r.client.Get(context.TODO(), types.NamespacedName{Name: hsName, Namespace: h.Namespace}, current)
-	if err != nil && errors.IsNotFound(err) {
-		reqLogger.Info("Creating a new Ingress", "Ingress.Namespace", desired.Namespace, "Ingress.Name", desired.Name)
-		if err := r.client.Create(context.TODO(), desired); err != nil {
-			reqLogger.Error(err, "Failed to create new Ingress", "Ingress.Namespace", desired.Namespace, "Ingress.Name", desired.Name)
-			return err
-		}
-	} else if err != nil {
-		reqLogger.Error(err, "Failed to get Ingress", "Ingress.Namespace", current.Namespace, "Ingress.Name", current.Name)
-		return err
-	} else if err := r.updateHealthServiceIngress(h, current, desired); err != nil {
+	result, err := common.CreateOrUpdate(r.client, h, desired, current, func() runtime.Object {
+		return r.updatedHealthServiceIngress(h, current, desired)
+	})
+	if err != nil {
+		reqLogger.Error(err, "Failed to create or update Ingress", "Ingress.Namespace", desired.Namespace, "Ingress.Name", desired.Name)
 		return err
 	}
+	if result != controllerutil.OperationResultNone {
+		reqLogger.Info("Ingress "+string(result), "Ingress.Namespace", desired.Namespace, "Ingress.Name", desired.Name)
+	}
 
 	return nil
 }
 
+// createOrUpdateHealthServiceConfigmap merges the cpnames shipped with the operator and the CloudPaks
+// of the cluster into the health service configmap. The cpnames added by users are kept, and so are
+// the other keys of the configmap.
 func (r *ReconcileHealthService) createOrUpdateHealthServiceConfigmap(h *operatorv1alpha1.HealthService) error {
-	hsName := healthResourceName
+	hsName := healthResourceName(h)
 	reqLogger := log.WithValues("HealthService.Namespace", h.Namespace, "HealthService.Name", h.Name)
 	labels := labelsForHealthService(hsName, h.Name)
 
-	//read configmap from yaml
-	yamlFile, err := os.Open("/manifests/system-healthcheck-service-config.yaml")
+	cm, shippedNames, err := readCPNamesManifest()
 	if err != nil {
-		reqLogger.Error(err, "Error opening System config file")
+		reqLogger.Error(err, "Failed to load the health service configmap shipped with the operator")
+		return err
 	}
-	// defer the closing of our jsonFile so that we can parse it later on
-	defer yamlFile.Close()
-	byteValue, _ := ioutil.ReadAll(yamlFile)
 
-	cm := new(corev1.ConfigMap)
-	if err := yaml.Unmarshal(byteValue, cm); err != nil {
-		reqLogger.Error(err, "Error parsing the configmap value from /manifests/system-healthcheck-service-config.yaml")
+	// duplicate CloudPaks are skipped and reported once the configmap is up to date
+	operatorNames, cloudPakErr := r.cloudPakNames(shippedNames)
+	if cloudPakErr != nil {
+		reqLogger.Error(cloudPakErr, "Failed to aggregate CloudPaks")
+		if operatorNames == nil {
+			return cloudPakErr
+		}
+	}
+	data, err := marshalCPNames(operatorNames)
+	if err != nil {
+		reqLogger.Error(err, "Failed to marshal cpnames")
 		return err
 	}
-	yamlFile.Close()
+	cm.Data[cpNamesKey] = data
 
 	//setup configmap name, namespace and labels
-	cm.ObjectMeta.Name = h.Spec.HealthService.ConfigmapName
-	cm.ObjectMeta.Namespace = h.Namespace
-	cm.ObjectMeta.Labels = labels
+	cm.ObjectMeta = metav1.ObjectMeta{
+		Name:        h.Spec.HealthService.ConfigmapName,
+		Namespace:   h.Namespace,
+		Labels:      labels,
+		Annotations: map[string]string{managedCPNamesAnnotation: managedCPNamesValue(operatorNames)},
+	}
 
 	// Set HealthService instance as the owner and controller
 	if err := controllerutil.SetControllerReference(h, cm, r.scheme); err != nil {
 		reqLogger.Error(err, "SetControllerReference failed", "configmap.Namespace", cm.Namespace, "configmap.Name", cm.Name)
+		return err
 	}
 
-	// Check if the ingress already exists, if not create a new one
+	// Check if the configmap already exists, if not create a new one
 	found := &corev1.ConfigMap{}
 	err = r.client.Get(context.TODO(), types.NamespacedName{Name: cm.Name, Namespace: cm.Namespace}, found)
 	if err != nil && errors.IsNotFound(err) {
@@ -182,15 +225,73 @@ func (r *ReconcileHealthService) createOrUpdateHealthServiceConfigmap(h *operato
 			reqLogger.Error(err, "Failed to create new configmap", "configmap.Namespace", cm.Namespace, "configmap.Name", cm.Name)
 			return err
 		}
+		return cloudPakErr
 	} else if err != nil {
-		reqLogger.Error(err, "Failed to get Ingress", "configmap.Namespace", found.Namespace, "configmap.Name", found.Name)
+		reqLogger.Error(err, "Failed to get configmap", "configmap.Namespace", cm.Namespace, "configmap.Name", cm.Name)
+		return err
+	}
+
+	if err := common.CheckOwnership(h, found); err != nil {
+		reqLogger.Error(err, "Skip reconcile: object is managed by another resource", "configmap.Namespace", found.Namespace, "configmap.Name", found.Name)
+		return err
+	}
+
+	if err := r.updateHealthServiceConfigmap(found, cm, operatorNames); err != nil {
+		return err
+	}
+	return cloudPakErr
+}
+
+func (r *ReconcileHealthService) updateHealthServiceConfigmap(current, desired *corev1.ConfigMap, operatorNames []cpName) error {
+	reqLogger := log.WithValues("configmap.Namespace", current.Namespace, "configmap.Name", current.Name)
+
+	// a broken cpnames.yaml is reported instead of being overwritten, since it may hold user entries
+	userNames, err := parseCPNames(current.Data[cpNamesKey])
+	if err != nil {
+		err = &configError{"ConfigmapInvalid", fmt.Errorf("failed to parse %s of configmap %s: %v", cpNamesKey, current.Name, err)}
+		reqLogger.Error(err, "Failed to merge cpnames")
 		return err
 	}
 
+	updated := current.DeepCopy()
+	updated.ObjectMeta.Labels = desired.ObjectMeta.Labels
+	updated.ObjectMeta.OwnerReferences = desired.ObjectMeta.OwnerReferences
+	if updated.ObjectMeta.Annotations == nil {
+		updated.ObjectMeta.Annotations = map[string]string{}
+	}
+	updated.ObjectMeta.Annotations[managedCPNamesAnnotation] = desired.ObjectMeta.Annotations[managedCPNamesAnnotation]
+	if updated.Data == nil {
+		updated.Data = map[string]string{}
+	}
+	for k, v := range desired.Data {
+		if _, ok := updated.Data[k]; !ok {
+			updated.Data[k] = v
+		}
+	}
+
+	merged := mergeCPNames(operatorNames, userNames, managedCPNames(current))
+	if !reflect.DeepEqual(merged, userNames) {
+		data, err := marshalCPNames(merged)
+		if err != nil {
+			reqLogger.Error(err, "Failed to marshal cpnames")
+			return err
+		}
+		updated.Data[cpNamesKey] = data
+	}
+
+	if reflect.DeepEqual(current, updated) {
+		return nil
+	}
+	reqLogger.Info("Updating configmap")
+	if err := r.client.Update(context.TODO(), updated); err != nil {
+		reqLogger.Error(err, "Failed to update configmap")
+		return err
+	}
 	return nil
 }
 
-func (r *ReconcileHealthService) updateHealthServiceDeployment(h *operatorv1alpha1.HealthService, current, desired *appsv1.Deployment) error {
+// updatedHealthServiceDeployment returns a copy of current in which the fields managed by the operator are set from desired
+func (r *ReconcileHealthService) updatedHealthServiceDeployment(h *operatorv1alpha1.HealthService, current, desired *appsv1.Deployment) *appsv1.Deployment {
 	reqLogger := log.WithValues("Deployment.Namespace", current.Namespace, "Deployment.Name", current.Name)
 
 	updated := current.DeepCopy()
@@ -198,32 +299,35 @@ func (r *ReconcileHealthService) updateHealthServiceDeployment(h *operatorv1alph
 	updated.Spec.Template.ObjectMeta.Annotations = desired.Spec.Template.ObjectMeta.Annotations
 	updated.Spec.Template.Spec.Containers = desired.Spec.Template.Spec.Containers
 	updated.Spec.Template.Spec.Volumes = desired.Spec.Template.Spec.Volumes
+	updated.Spec.Template.Spec.ServiceAccountName = desired.Spec.Template.Spec.ServiceAccountName
+	updated.Spec.Template.Spec.NodeSelector = desired.Spec.Template.Spec.NodeSelector
+	updated.Spec.Template.Spec.Tolerations = desired.Spec.Template.Spec.Tolerations
+	updated.Spec.Template.Spec.HostNetwork = desired.Spec.Template.Spec.HostNetwork
+	updated.Spec.Template.Spec.DNSPolicy = desired.Spec.Template.Spec.DNSPolicy
 
-	reqLogger.Info("Updating Deployment")
 	// Set HealthService instance as the owner and controller
 	if err := controllerutil.SetControllerReference(h, updated, r.scheme); err != nil {
 		reqLogger.Error(err, "SetControllerReference failed", "Deployment.Namespace", updated.Namespace, "Deployment.Name", updated.Name)
 	}
 
-	if err := r.client.Update(context.TODO(), updated); err != nil {
-		reqLogger.Error(err, "Failed to update Deployment", "Deployment.Namespace", updated.Namespace, "Deployment.Name", updated.Name)
-		return err
-	}
-
-	return nil
+	return updated
 }
 
 func (r *ReconcileHealthService) desiredHealthServiceDeployment(h *operatorv1alpha1.HealthService) *appsv1.Deployment {
-	hsName := healthResourceName
+	hsName := healthResourceName(h)
 	cfgName := h.Spec.HealthService.ConfigmapName
 	labels := labelsForHealthService(hsName, h.Name)
 	annotations := annotationsForHealthService()
-	serviceAccountName := "ibm-healthcheck-operator-cluster"
+	serviceAccountName := defaultServiceAccountName
+	if h.Spec.HealthService.ServiceAccountName != "" {
+		serviceAccountName = h.Spec.HealthService.ServiceAccountName
+	}
 
 	reqLogger := log.WithValues("HealthService.Namespace", h.Namespace, "HealthService.Name", h.Name)
 	reqLogger.Info("Building HealthService Deployment", "Deployment.Namespace", h.Namespace, "Deployment.Name", hsName)
 
 	hsResources := common.GetResources(&h.Spec.HealthService.Resources)
+	image, pullPolicy := common.ResolveImage(&h.Spec.HealthService.Image, constant.HealthServiceImageEnv, constant.DefaultHealthServiceImage)
 	hsReplicas := int32(1)
 	if h.Spec.HealthService.Replicas > 0 {
 		hsReplicas = h.Spec.HealthService.Replicas
@@ -249,16 +353,18 @@ func (r *ReconcileHealthService) desiredHealthServiceDeployment(h *operatorv1alp
 				},
 				Spec: corev1.PodSpec{
 					TerminationGracePeriodSeconds: &gracePeriod,
-					HostNetwork:                   false,
+					HostNetwork:                   h.Spec.HealthService.HostNetwork,
+					DNSPolicy:                     common.DNSPolicy(h.Spec.HealthService.HostNetwork),
 					HostPID:                       false,
 					HostIPC:                       false,
 					ServiceAccountName:            serviceAccountName,
+					NodeSelector:                  h.Spec.HealthService.NodeSelector,
 					Containers: []corev1.Container{
 						{
 							Name:            hsName,
-							Image:           os.Getenv("SYSTEM_HEALTHCHECK_SERVICE_IMAGE"),
-							ImagePullPolicy: corev1.PullIfNotPresent,
-							SecurityContext: &commonSecurityContext,
+							Image:           image,
+							ImagePullPolicy: pullPolicy,
+							SecurityContext: common.MergeSecurityContext(&commonSecurityContext, &h.Spec.HealthService.SecurityContext),
 							Env: []corev1.EnvVar{
 								{
 									Name: "HEALTHNAMESPACE",
@@ -276,6 +382,10 @@ func (r *ReconcileHealthService) desiredHealthServiceDeployment(h *operatorv1alp
 									Name:  "LOGLEVEL",
 									Value: "1",
 								},
+								{
+									Name:  "MEMCACHEDHOST",
+									Value: memSvcName(memResourceName(h)),
+								},
 								{
 									Name:  "MEMCACHEDPORT",
 									Value: "11211",
@@ -334,17 +444,7 @@ func (r *ReconcileHealthService) desiredHealthServiceDeployment(h *operatorv1alp
 							},
 						},
 					},
-					Tolerations: []corev1.Toleration{
-						{
-							Key:      "dedicated",
-							Operator: corev1.TolerationOpExists,
-							Effect:   corev1.TaintEffectNoSchedule,
-						},
-						{
-							Key:      "CriticalAddonsOnly",
-							Operator: corev1.TolerationOpExists,
-						},
-					},
+					Tolerations: common.MergeTolerations(defaultTolerations, h.Spec.HealthService.Tolerations),
 					Volumes: []corev1.Volume{
 						{
 							Name: "tmp-volume",
@@ -383,7 +483,8 @@ func (r *ReconcileHealthService) desiredHealthServiceDeployment(h *operatorv1alp
 	return dep
 }
 
-func (r *ReconcileHealthService) updateHealthServiceService(h *operatorv1alpha1.HealthService, current, desired *corev1.Service) error {
+// updatedHealthServiceService returns a copy of current in which the fields managed by the operator are set from desired
+func (r *ReconcileHealthService) updatedHealthServiceService(h *operatorv1alpha1.HealthService, current, desired *corev1.Service) *corev1.Service {
 	reqLogger := log.WithValues("Service.Namespace", current.Namespace, "Service.Name", current.Name)
 
 	updated := current.DeepCopy()
@@ -392,22 +493,16 @@ func (r *ReconcileHealthService) updateHealthServiceService(h *operatorv1alpha1.
 	updated.Spec.Selector = desired.Spec.Selector
 	updated.Spec.Type = desired.Spec.Type
 
-	reqLogger.Info("Updating Service")
 	// Set HealthService instance as the owner and controller
 	if err := controllerutil.SetControllerReference(h, updated, r.scheme); err != nil {
 		reqLogger.Error(err, "SetControllerReference failed", "Service.Namespace", updated.Namespace, "Service.Name", updated.Name)
 	}
 
-	if err := r.client.Update(context.TODO(), updated); err != nil {
-		reqLogger.Error(err, "Failed to update Service", "Service.Namespace", updated.Namespace, "Service.Name", updated.Name)
-		return err
-	}
-
-	return nil
+	return updated
 }
 
 func (r *ReconcileHealthService) desiredHealthServiceService(h *operatorv1alpha1.HealthService) *corev1.Service {
-	hsName := healthResourceName
+	hsName := healthResourceName(h)
 	labels := labelsForHealthService(hsName, h.Name)
 
 	reqLogger := log.WithValues("HealthService.Namespace", h.Namespace, "HealthService.Name", h.Name)
@@ -440,34 +535,55 @@ func (r *ReconcileHealthService) desiredHealthServiceService(h *operatorv1alpha1
 	return svc
 }
 
-func (r *ReconcileHealthService) updateHealthServiceIngress(h *operatorv1alpha1.HealthService, current, desired *networkingv1.Ingress) error {
+// updatedHealthServiceIngress returns a copy of current in which the fields managed by the operator are set from desired
+func (r *ReconcileHealthService) updatedHealthServiceIngress(h *operatorv1alpha1.HealthService, current, desired *networkingv1.Ingress) *networkingv1.Ingress {
 	reqLogger := log.WithValues("Ingress.Namespace", current.Namespace, "Ingress.Name", current.Name)
 
 	updated := current.DeepCopy()
 	updated.ObjectMeta.Labels = desired.ObjectMeta.Labels
 	updated.ObjectMeta.Annotations = desired.ObjectMeta.Annotations
+	updated.Spec.IngressClassName = desired.Spec.IngressClassName
 	updated.Spec.Rules = desired.Spec.Rules
+	updated.Spec.TLS = desired.Spec.TLS
 
-	reqLogger.Info("Updating Ingress")
 	// Set HealthService instance as the owner and controller
 	if err := controllerutil.SetControllerReference(h, updated, r.scheme); err != nil {
 		reqLogger.Error(err, "SetControllerReference failed", "Ingress.Namespace", updated.Namespace, "Ingress.Name", updated.Name)
 	}
 
-	if err := r.client.Update(context.TODO(), updated); err != nil {
-		reqLogger.Error(err, "Failed to update Ingress", "Ingress.Namespace", updated.Namespace, "Ingress.Name", updated.Name)
+	return updated
+}
+
+// deleteHealthServiceIngress deletes the Ingress when it is disabled
+func (r *ReconcileHealthService) deleteHealthServiceIngress(h *operatorv1alpha1.HealthService) error {
+	reqLogger := log.WithValues("HealthService.Namespace", h.Namespace, "HealthService.Name", h.Name)
+
+	current := &networkingv1.Ingress{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: healthResourceName(h), Namespace: h.Namespace}, current)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		reqLogger.Error(err, "Failed to get Ingress", "Ingress.Namespace", current.Namespace, "Ingress.Name", current.Name)
 		return err
 	}
+	if !metav1.IsControlledBy(current, h) {
+		return nil
+	}
 
-	return nil
+	reqLogger.Info("Deleting disabled Ingress", "Ingress.Namespace", current.Namespace, "Ingress.Name", current.Name)
+	if err := r.client.Delete(context.TODO(), current); err != nil && !errors.IsNotFound(err) {
+		reqLogger.Error(err, "Failed to delete Ingress", "Ingress.Namespace", current.Namespace, "Ingress.Name", current.Name)
+		return err
+	}
 
+	return nil
 }
 
 func (r *ReconcileHealthService) desiredHealthServiceIngress(h *operatorv1alpha1.HealthService) *networkingv1.Ingress {
-	hsName := healthResourceName
+	hsName := healthResourceName(h)
 	labels := labelsForHealthService(hsName, h.Name)
-	annotations := annotationsForHealthServiceIngress()
-	pathType := networkingv1.PathType(constant.IngPathType)
+	annotations := common.IngressAnnotations(&h.Spec.Ingress, annotationsForHealthServiceIngress())
 
 	reqLogger := log.WithValues("HealthService.Namespace", h.Namespace, "HealthService.Name", h.Name)
 	reqLogger.Info("Building HealthService Ingress", "Ingress.Namespace", h.Namespace, "Ingress.Name", hsName)
@@ -479,28 +595,7 @@ func (r *ReconcileHealthService) desiredHealthServiceIngress(h *operatorv1alpha1
 			Labels:      labels,
 			Annotations: annotations,
 		},
-		Spec: networkingv1.IngressSpec{
-			Rules: []networkingv1.IngressRule{
-				{
-					IngressRuleValue: networkingv1.IngressRuleValue{
-						HTTP: &networkingv1.HTTPIngressRuleValue{
-							Paths: []networkingv1.HTTPIngressPath{
-								{
-									Path:     constant.HealthServiceRoute,
-									PathType: &pathType,
-									Backend: networkingv1.IngressBackend{
-										Service: &networkingv1.IngressServiceBackend{
-											Name: hsName,
-											Port: networkingv1.ServiceBackendPort{Number: constant.HealthServicePort},
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-		},
+		Spec: common.IngressSpec(&h.Spec.Ingress, constant.HealthServiceRoute, hsName, constant.HealthServicePort),
 	}
 
 	// Set HealthService instance as the owner and controller
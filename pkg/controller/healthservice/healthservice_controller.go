@@ -18,16 +18,22 @@ package healthservice
 
 import (
 	"context"
+	goerrors "errors"
+	"time"
 
+	clusterhealthv1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/clusterhealth/v1"
 	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -42,6 +48,9 @@ import (
 
 var log = logf.Log.WithName("controller_healthservice")
 
+// statusRequeueInterval is how often the status is refreshed while the operands are not ready
+var statusRequeueInterval = 30 * time.Second
+
 var (
 	// watchedResources contains the resources we will watch and reconcile when changed
 	watchedResources = []schema.GroupVersionKind{
@@ -79,7 +88,7 @@ func Add(mgr manager.Manager) error {
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileHealthService{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+	return &ReconcileHealthService{client: mgr.GetClient(), reader: mgr.GetAPIReader(), mapper: mgr.GetRESTMapper(), scheme: mgr.GetScheme()}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -130,6 +139,15 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Pods are owned by the ReplicaSets, they are mapped to the HealthService which owns their Deployment
+	// so that the pod states in the status are refreshed
+	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: common.OperandPodRequests(mgr.GetClient(), "HealthService"),
+	})
+	if err != nil {
+		return err
+	}
+
 	// Configmap
 	err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestForOwner{
 		IsController: true,
@@ -139,9 +157,64 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// The registry mirrors apply to the images of every HealthService
+	err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			if !common.IsRegistryMirrors(a.Meta) {
+				return nil
+			}
+			return healthServiceRequests(mgr.GetClient())
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// CloudPak, when served by the cluster, requeues every HealthService since they all aggregate the CloudPaks
+	if _, ok := common.ServedKind(mgr.GetRESTMapper(), cloudPakGroupKind); ok {
+		err = c.Watch(&source.Kind{Type: &clusterhealthv1.CloudPak{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+				return healthServiceRequests(mgr.GetClient())
+			}),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Route and HTTPRoute, when served by the cluster
+	for _, gvk := range common.ServedExposureKinds(mgr.GetRESTMapper()) {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		err = c.Watch(&source.Kind{Type: u}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &operatorv1alpha1.HealthService{},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// healthServiceRequests returns a request for every HealthService watched by the operator
+func healthServiceRequests(c client.Client) []reconcile.Request {
+	healthServiceList := &operatorv1alpha1.HealthServiceList{}
+	if err := c.List(context.TODO(), healthServiceList); err != nil {
+		log.Error(err, "Failed to list HealthServices")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, h := range healthServiceList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: h.Namespace, Name: h.Name},
+		})
+	}
+	return requests
+}
+
 // blank assignment to verify that ReconcileHealthService implements reconcile.Reconciler
 var _ reconcile.Reconciler = &ReconcileHealthService{}
 
@@ -151,6 +224,9 @@ type ReconcileHealthService struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
+	// reader reads from the apiserver, the status is patched from the latest object read with it
+	reader client.Reader
+	mapper meta.RESTMapper
 	scheme *runtime.Scheme
 }
 
@@ -181,37 +257,74 @@ func (r *ReconcileHealthService) Reconcile(request reconcile.Request) (reconcile
 		return reconcile.Result{}, err
 	}
 
-	if err = r.createOrUpdateMemcachedDeploy(healthService); err != nil {
-		reqLogger.Error(err, "Failed to create or update Deployment for memcached")
+	// The names of the operands are decided before they are created, and kept
+	if err := r.recordResourceNames(healthService); err != nil {
+		reqLogger.Error(err, "Failed to record the names of the operands")
 		return reconcile.Result{}, err
 	}
 
-	if err = r.createOrUpdateMemcachedService(healthService); err != nil {
-		reqLogger.Error(err, "Failed to create or update Service for memcached")
+	// The status is gathered in healthService and written once, as a patch from original
+	original := healthService.DeepCopy()
+	reconcileErr := r.reconcileOperands(healthService)
+
+	// Update the HealthService conditions from the state of the operands
+	if err = r.updateHealthServiceStatus(original, healthService, reconcileErr); err != nil {
 		return reconcile.Result{}, err
 	}
+	if reconcileErr != nil {
+		return reconcile.Result{}, reconcileErr
+	}
 
-	if err = r.createOrUpdateHealthServiceConfigmap(healthService); err != nil {
+	// Endpoints are not watched, check again until all the operands are ready
+	if healthService.Status.Phase != operatorv1alpha1.PhaseRunning {
+		return reconcile.Result{RequeueAfter: statusRequeueInterval}, nil
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// reconcileOperands creates or updates all the resources managed by the HealthService
+func (r *ReconcileHealthService) reconcileOperands(healthService *operatorv1alpha1.HealthService) error {
+	reqLogger := log.WithValues("Request.Namespace", healthService.Namespace, "Request.Name", healthService.Name)
+
+	if err := r.createOrUpdateMemcachedDeploy(healthService); err != nil {
+		reqLogger.Error(err, "Failed to create or update Deployment for memcached")
+		return err
+	}
+
+	if err := r.createOrUpdateMemcachedService(healthService); err != nil {
+		reqLogger.Error(err, "Failed to create or update Service for memcached")
+		return err
+	}
+
+	// a configuration error is reported once the other operands are reconciled
+	var configErr *configError
+	if err := r.createOrUpdateHealthServiceConfigmap(healthService); err != nil {
 		reqLogger.Error(err, "Failed to create or update configmap for health service")
-		return reconcile.Result{}, err
+		if !goerrors.As(err, &configErr) {
+			return err
+		}
 	}
 
-	if err = r.createOrUpdateHealthServiceDeploy(healthService); err != nil {
+	if err := r.createOrUpdateHealthServiceDeploy(healthService); err != nil {
 		reqLogger.Error(err, "Failed to create or update Deployment for health service")
-		return reconcile.Result{}, err
+		return err
 	}
 
-	if err = r.createOrUpdateHealthServiceService(healthService); err != nil {
+	if err := r.createOrUpdateHealthServiceService(healthService); err != nil {
 		reqLogger.Error(err, "Failed to create or update Service for health service")
-		return reconcile.Result{}, err
+		return err
 	}
 
-	if err = r.createOrUpdateHealthServiceIngress(healthService); err != nil {
-		reqLogger.Error(err, "Failed to create or update Ingress for health service")
-		return reconcile.Result{}, err
+	if err := r.createOrUpdateHealthServiceExposure(healthService); err != nil {
+		reqLogger.Error(err, "Failed to create or update Ingress, Route or HTTPRoute for health service")
+		return err
 	}
 
-	return reconcile.Result{}, nil
+	if configErr != nil {
+		return configErr
+	}
+	return nil
 }
 
 // Watch configmap resources managed by the operator
@@ -18,27 +18,42 @@ package healthservice
 
 import (
 	"context"
-	"os"
-	"reflect"
 
 	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
 	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
+	constant "github.com/IBM/ibm-healthcheck-operator/pkg/controller/constant"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/runtime"
 	intstr "k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
-var memSvcName = "memcached"
-var memResourceName = "icp-memcached"
+// defaultServiceAccountName is used by the operands when spec.serviceAccountName is empty
+var defaultServiceAccountName = "ibm-healthcheck-operator-cluster"
 
 var trueVar = true
 var falseVar = false
+
+// defaultTolerations are always added to the operand pods, a custom toleration
+// with the same key and effect replaces the default one
+var defaultTolerations = []corev1.Toleration{
+	{
+		Key:      "dedicated",
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffectNoSchedule,
+	},
+	{
+		Key:      "CriticalAddonsOnly",
+		Operator: corev1.TolerationOpExists,
+	},
+}
+
+// commonSecurityContext is the default container security context, every field set in
+// spec.securityContext overrides the default value
 var commonSecurityContext = corev1.SecurityContext{
 	AllowPrivilegeEscalation: &falseVar,
 	Privileged:               &falseVar,
@@ -52,29 +67,30 @@ var commonSecurityContext = corev1.SecurityContext{
 }
 
 func (r *ReconcileHealthService) createOrUpdateMemcachedDeploy(h *operatorv1alpha1.HealthService) error {
-	memName := memResourceName
+	memName := memResourceName(h)
 	reqLogger := log.WithValues("HealthService.Namespace", h.Namespace, "HealthService.Name", h.Name)
 
 	// Define a new deployment
 	desired := r.desiredMemcachedDeployment(h)
-	// Check if the deployment already exists, if not create a new one
-	current := &appsv1.Deployment{}
-	err := r.client.Get(context.TODO(), types.NamespacedName{Name: memName, Namespace: h.Namespace}, current)
-
-	if err != nil && errors.IsNotFound(err) {
-		reqLogger.Info("Creating a new Deployment", "Deployment.Namespace", desired.Namespace, "Deployment.Name", desired.Name)
-		if err := r.client.Create(context.TODO(), desired); err != nil {
-			reqLogger.Error(err, "Failed to create new Deployment", "Deployment.Namespace", desired.Namespace, "Deployment.Name", desired.Name)
-			return err
-		}
-	} else if err != nil {
-		reqLogger.Error(err, "Failed to get Deployment", "Deployment.Namespace", current.Namespace, "Deployment.Name", current.Name)
+	// Pull the images from their registry mirror
+	if err := common.SetMirroredImages(r.client, &desired.Spec.Template); err != nil {
+		reqLogger.Error(err, "Failed to get the registry mirrors", "Deployment.Namespace", desired.Namespace, "Deployment.Name", desired.Name)
 		return err
-	} else if err := r.updateMemcachedDeployment(h, current, desired); err != nil {
+	}
+	// Create the deployment, or update it if it drifted from the desired state
+	current := &appsv1.Deployment{}
+	result, err := common.CreateOrUpdate(r.client, h, desired, current, func() runtime.Object {
+		return r.updatedMemcachedDeployment(h, current, desired)
+	})
+	if err != nil {
+		reqLogger.Error(err, "Failed to create or update Deployment", "Deployment.Namespace", desired.Namespace, "Deployment.Name", desired.Name)
 		return err
 	}
+	if result != controllerutil.OperationResultNone {
+		reqLogger.Info("Deployment "+string(result), "Deployment.Namespace", desired.Namespace, "Deployment.Name", desired.Name)
+	}
 
-	// Update the HealthService status with the pod names
+	// Record the pod states in the HealthService status
 	podList := &corev1.PodList{}
 	listOpts := []client.ListOption{
 		client.InNamespace(h.Namespace),
@@ -84,17 +100,10 @@ func (r *ReconcileHealthService) createOrUpdateMemcachedDeploy(h *operatorv1alph
 		reqLogger.Error(err, "Failed to list pods", "h.Namespace", h.Namespace, "h.Name", memName)
 		return err
 	}
-	podNames := common.GetPodNames(podList.Items)
-
-	// Update status.MemcachedNodes if needed
-	if !reflect.DeepEqual(podNames, h.Status.MemcachedNodes) {
-		h.Status.MemcachedNodes = podNames
-		err := r.client.Status().Update(context.TODO(), h)
-		if err != nil {
-			reqLogger.Error(err, "Failed to update HealthService status")
-			return err
-		}
-	}
+	// The status is written once at the end of the reconcile
+	h.Status.MemcachedNodes = common.GetPodNames(podList.Items)
+	h.Status.MemcachedPods = common.PodStatuses(podList.Items)
+	h.Status.MemcachedImage = common.RunningImage(desired.Spec.Template.Spec.Containers[0].Image, podList.Items)
 
 	return nil
 }
@@ -104,54 +113,52 @@ func (r *ReconcileHealthService) createOrUpdateMemcachedService(h *operatorv1alp
 
 	// Define a new service
 	desired := r.desiredMemcachedService(h)
-	// Check if the service already exists, if not create a new one
+	// Create the service, or update it if it drifted from the desired state
 	current := &corev1.Service{}
-	err := r.client.Get(context.TODO(), types.NamespacedName{Name: memSvcName, Namespace: h.Namespace}, current)
-
-	if err != nil && errors.IsNotFound(err) {
-		reqLogger.Info("Creating a new Service", "Service.Namespace", desired.Namespace, "Service.Name", desired.Name)
-		if err := r.client.Create(context.TODO(), desired); err != nil {
-			reqLogger.Error(err, "Failed to create new Service", "Service.Namespace", desired.Namespace, "Service.Name", desired.Name)
-			return err
-		}
-	} else if err != nil {
-		reqLogger.Error(err, "Failed to get Service", "Service.Namespace", current.Namespace, "Service.Name", current.Name)
-		return err
-	} else if err := r.updateMemcachedService(h, current, desired); err != nil {
+	result, err := common.CreateOrUpdate(r.client, h, desired, current, func() runtime.Object {
+		return r.updatedMemcachedService(h, current, desired)
+	})
+	if err != nil {
+		reqLogger.Error(err, "Failed to create or update Service", "Service.Namespace", desired.Namespace, "Service.Name", desired.Name)
 		return err
 	}
+	if result != controllerutil.OperationResultNone {
+		reqLogger.Info("Service "+string(result), "Service.Namespace", desired.Namespace, "Service.Name", desired.Name)
+	}
 
 	return nil
 }
 
-func (r *ReconcileHealthService) updateMemcachedDeployment(h *operatorv1alpha1.HealthService, current, desired *appsv1.Deployment) error {
+// updatedMemcachedDeployment returns a copy of current in which the fields managed by the operator are set from desired
+func (r *ReconcileHealthService) updatedMemcachedDeployment(h *operatorv1alpha1.HealthService, current, desired *appsv1.Deployment) *appsv1.Deployment {
 	reqLogger := log.WithValues("Deployment.Namespace", current.Namespace, "Deployment.Name", current.Name)
 
 	updated := current.DeepCopy()
 	updated.Spec.Replicas = desired.Spec.Replicas
 	updated.Spec.Template.ObjectMeta.Annotations = desired.Spec.Template.ObjectMeta.Annotations
 	updated.Spec.Template.Spec.Containers = desired.Spec.Template.Spec.Containers
+	updated.Spec.Template.Spec.ServiceAccountName = desired.Spec.Template.Spec.ServiceAccountName
+	updated.Spec.Template.Spec.NodeSelector = desired.Spec.Template.Spec.NodeSelector
+	updated.Spec.Template.Spec.Tolerations = desired.Spec.Template.Spec.Tolerations
 
-	reqLogger.Info("Updating Deployment")
 	// Set HealthService instance as the owner and controller
 	if err := controllerutil.SetControllerReference(h, updated, r.scheme); err != nil {
 		reqLogger.Error(err, "SetControllerReference failed", "Deployment.Namespace", updated.Namespace, "Deployment.Name", updated.Name)
 	}
 
-	if err := r.client.Update(context.TODO(), updated); err != nil {
-		reqLogger.Error(err, "Failed to update Deployment", "Deployment.Namespace", updated.Namespace, "Deployment.Name", updated.Name)
-		return err
-	}
-
-	return nil
+	return updated
 }
 
 func (r *ReconcileHealthService) desiredMemcachedDeployment(h *operatorv1alpha1.HealthService) *appsv1.Deployment {
-	memName := memResourceName
+	memName := memResourceName(h)
+	portName := memcachedPortName(memName)
 	labels := labelsForMemcached(memName, h.Name)
 	annotations := annotationsForMemcached()
 	defaultCommand := []string{"memcached", "-m 64", "-o", "modern", "-v"}
-	serviceAccountName := "ibm-healthcheck-operator-cluster"
+	serviceAccountName := defaultServiceAccountName
+	if h.Spec.Memcached.ServiceAccountName != "" {
+		serviceAccountName = h.Spec.Memcached.ServiceAccountName
+	}
 	if h.Spec.Memcached.Command != nil && len(h.Spec.Memcached.Command) > 0 {
 		defaultCommand = h.Spec.Memcached.Command
 	}
@@ -160,6 +167,7 @@ func (r *ReconcileHealthService) desiredMemcachedDeployment(h *operatorv1alpha1.
 	reqLogger.Info("Building Memcached Deployment", "Deployment.Namespace", h.Namespace, "Deployment.Name", memName)
 
 	hmResources := common.GetResources(&h.Spec.Memcached.Resources)
+	image, pullPolicy := common.ResolveImage(&h.Spec.Memcached.Image, constant.MemcachedImageEnv, constant.DefaultMemcachedImage)
 	hmReplicas := int32(1)
 	if h.Spec.Memcached.Replicas > 0 {
 		hmReplicas = h.Spec.Memcached.Replicas
@@ -187,20 +195,21 @@ func (r *ReconcileHealthService) desiredMemcachedDeployment(h *operatorv1alpha1.
 					HostPID:            false,
 					HostIPC:            false,
 					ServiceAccountName: serviceAccountName,
+					NodeSelector:       h.Spec.Memcached.NodeSelector,
 					Containers: []corev1.Container{{
 						Name:            memName,
-						Image:           os.Getenv("ICP_MEMCACHED_IMAGE"),
-						ImagePullPolicy: corev1.PullIfNotPresent,
+						Image:           image,
+						ImagePullPolicy: pullPolicy,
 						Command:         defaultCommand,
 						Ports: []corev1.ContainerPort{{
 							ContainerPort: 11211,
-							Name:          memName,
+							Name:          portName,
 						}},
-						SecurityContext: &commonSecurityContext,
+						SecurityContext: common.MergeSecurityContext(&commonSecurityContext, &h.Spec.Memcached.SecurityContext),
 						LivenessProbe: &corev1.Probe{
 							Handler: corev1.Handler{
 								TCPSocket: &corev1.TCPSocketAction{
-									Port: intstr.FromString(memName),
+									Port: intstr.FromString(portName),
 								},
 							},
 							InitialDelaySeconds: 30,
@@ -209,7 +218,7 @@ func (r *ReconcileHealthService) desiredMemcachedDeployment(h *operatorv1alpha1.
 						ReadinessProbe: &corev1.Probe{
 							Handler: corev1.Handler{
 								TCPSocket: &corev1.TCPSocketAction{
-									Port: intstr.IntOrString{Type: intstr.String, StrVal: memName},
+									Port: intstr.IntOrString{Type: intstr.String, StrVal: portName},
 								},
 							},
 							InitialDelaySeconds: 5,
@@ -217,17 +226,7 @@ func (r *ReconcileHealthService) desiredMemcachedDeployment(h *operatorv1alpha1.
 						},
 						Resources: *hmResources,
 					}},
-					Tolerations: []corev1.Toleration{
-						{
-							Key:      "dedicated",
-							Operator: corev1.TolerationOpExists,
-							Effect:   corev1.TaintEffectNoSchedule,
-						},
-						{
-							Key:      "CriticalAddonsOnly",
-							Operator: corev1.TolerationOpExists,
-						},
-					},
+					Tolerations: common.MergeTolerations(defaultTolerations, h.Spec.Memcached.Tolerations),
 				},
 			},
 		},
@@ -241,7 +240,8 @@ func (r *ReconcileHealthService) desiredMemcachedDeployment(h *operatorv1alpha1.
 	return dep
 }
 
-func (r *ReconcileHealthService) updateMemcachedService(h *operatorv1alpha1.HealthService, current, desired *corev1.Service) error {
+// updatedMemcachedService returns a copy of current in which the fields managed by the operator are set from desired
+func (r *ReconcileHealthService) updatedMemcachedService(h *operatorv1alpha1.HealthService, current, desired *corev1.Service) *corev1.Service {
 	reqLogger := log.WithValues("Service.Namespace", current.Namespace, "Service.Name", current.Name)
 
 	updated := current.DeepCopy()
@@ -250,30 +250,26 @@ func (r *ReconcileHealthService) updateMemcachedService(h *operatorv1alpha1.Heal
 	updated.Spec.Selector = desired.Spec.Selector
 	updated.Spec.ClusterIP = desired.Spec.ClusterIP
 
-	reqLogger.Info("Updating Service")
 	// Set HealthService instance as the owner and controller
 	if err := controllerutil.SetControllerReference(h, updated, r.scheme); err != nil {
 		reqLogger.Error(err, "SetControllerReference failed", "Service.Namespace", updated.Namespace, "Service.Name", updated.Name)
 	}
 
-	if err := r.client.Update(context.TODO(), updated); err != nil {
-		reqLogger.Error(err, "Failed to update Service", "Service.Namespace", updated.Namespace, "Service.Name", updated.Name)
-		return err
-	}
-
-	return nil
+	return updated
 }
 
 func (r *ReconcileHealthService) desiredMemcachedService(h *operatorv1alpha1.HealthService) *corev1.Service {
-	memName := memResourceName
+	memName := memResourceName(h)
+	svcName := memSvcName(memName)
+	portName := memcachedPortName(memName)
 	labels := labelsForMemcached(memName, h.Name)
 
 	reqLogger := log.WithValues("HealthService.Namespace", h.Namespace, "HealthService.Name", h.Name)
-	reqLogger.Info("Building Memcached Service", "Service.Namespace", h.Namespace, "Service.Name", memSvcName)
+	reqLogger.Info("Building Memcached Service", "Service.Namespace", h.Namespace, "Service.Name", svcName)
 
 	svc := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:            memSvcName,
+			Name:            svcName,
 			Namespace:       h.Namespace,
 			Labels:          labels,
 			ResourceVersion: "",
@@ -281,9 +277,9 @@ func (r *ReconcileHealthService) desiredMemcachedService(h *operatorv1alpha1.Hea
 		Spec: corev1.ServiceSpec{
 			Ports: []corev1.ServicePort{
 				{
-					Name:       memName,
+					Name:       portName,
 					Port:       11211,
-					TargetPort: intstr.IntOrString{Type: intstr.String, StrVal: memName},
+					TargetPort: intstr.IntOrString{Type: intstr.String, StrVal: portName},
 				},
 			},
 			Selector:  labels,
@@ -293,7 +289,7 @@ func (r *ReconcileHealthService) desiredMemcachedService(h *operatorv1alpha1.Hea
 
 	// Set HealthService instance as the owner and controller
 	if err := controllerutil.SetControllerReference(h, svc, r.scheme); err != nil {
-		reqLogger.Error(err, "SetControllerReference failed", "Service.Namespace", h.Namespace, "Service.Name", memSvcName)
+		reqLogger.Error(err, "SetControllerReference failed", "Service.Namespace", h.Namespace, "Service.Name", svcName)
 	}
 
 	return svc
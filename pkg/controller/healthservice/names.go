@@ -0,0 +1,139 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package healthservice
+
+import (
+	"context"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// names of the objects created by the operator before they were derived from the HealthService,
+// they are still used by a HealthService which already manages them
+const (
+	legacyHealthResourceName = "system-healthcheck-service"
+	legacyMemResourceName    = "icp-memcached"
+	legacyMemSvcName         = "memcached"
+)
+
+// annotations of the HealthService which record the names of the objects not named in the spec,
+// so that the names don't change once the objects are created
+const (
+	healthResourceNameAnnotation = "healthcheck.ibm.com/health-service-name"
+	memResourceNameAnnotation    = "healthcheck.ibm.com/memcached-name"
+)
+
+// memPortName is the memcached port name when the deployment name is too long for a port name
+const memPortName = "memcached"
+
+// healthResourceName returns the name of the health service deployment, service and ingress.
+// It is spec.healthService.name, else the name recorded by recordResourceNames.
+func healthResourceName(h *operatorv1alpha1.HealthService) string {
+	if h.Spec.HealthService.Name != "" {
+		return h.Spec.HealthService.Name
+	}
+	if name := h.Annotations[healthResourceNameAnnotation]; name != "" {
+		return name
+	}
+	return h.Name
+}
+
+// memResourceName returns the name of the memcached deployment.
+// It is spec.memcached.name, else the name recorded by recordResourceNames.
+func memResourceName(h *operatorv1alpha1.HealthService) string {
+	if h.Spec.Memcached.Name != "" {
+		return h.Spec.Memcached.Name
+	}
+	if name := h.Annotations[memResourceNameAnnotation]; name != "" {
+		return name
+	}
+	return h.Name + "-memcached"
+}
+
+// recordResourceNames decides once the names of the objects which are not named in the spec, and
+// records them in annotations of the HealthService. A name is the legacy name if the HealthService
+// already manages the legacy deployment, else it is derived from the HealthService name.
+func (r *ReconcileHealthService) recordResourceNames(h *operatorv1alpha1.HealthService) error {
+	names := []struct {
+		annotation string
+		specName   string
+		legacy     string
+		name       string
+	}{
+		{healthResourceNameAnnotation, h.Spec.HealthService.Name, legacyHealthResourceName, h.Name},
+		{memResourceNameAnnotation, h.Spec.Memcached.Name, legacyMemResourceName, h.Name + "-memcached"},
+	}
+
+	annotations := map[string]string{}
+	for k, v := range h.Annotations {
+		annotations[k] = v
+	}
+	recorded := false
+	for _, n := range names {
+		if n.specName != "" || annotations[n.annotation] != "" {
+			continue
+		}
+		legacy, err := r.adoptLegacyDeployment(h, n.legacy)
+		if err != nil {
+			return err
+		}
+		annotations[n.annotation] = n.name
+		if legacy {
+			annotations[n.annotation] = n.legacy
+		}
+		recorded = true
+	}
+	if !recorded {
+		return nil
+	}
+	h.Annotations = annotations
+	return r.client.Update(context.TODO(), h)
+}
+
+// memSvcName returns the name of the memcached service, which keeps its legacy name
+// together with the legacy memcached deployment
+func memSvcName(memName string) string {
+	if memName == legacyMemResourceName {
+		return legacyMemSvcName
+	}
+	return memName
+}
+
+// memcachedPortName returns the name of the memcached container port, port names are limited to 15 characters
+func memcachedPortName(memName string) string {
+	if len(memName) > 15 {
+		return memPortName
+	}
+	return memName
+}
+
+// adoptLegacyDeployment returns true if the legacy deployment exists and is not managed by another object
+func (r *ReconcileHealthService) adoptLegacyDeployment(h *operatorv1alpha1.HealthService, name string) (bool, error) {
+	dep := &appsv1.Deployment{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: h.Namespace}, dep); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return common.IsAdoptable(h, dep), nil
+}
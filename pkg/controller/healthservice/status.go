@@ -0,0 +1,199 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package healthservice
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// componentStatus is the observed state of one of the HealthService operands
+type componentStatus struct {
+	ready       bool
+	progressing bool
+	failed      bool
+	reason      string
+	message     string
+}
+
+// updateHealthServiceStatus computes the Ready, Progressing and Degraded conditions and the phase
+// from the rollout status of the operands, and writes the status gathered during the reconcile once,
+// as a patch from original. reconcileErr is the error, if any, returned while creating or updating the operands.
+func (r *ReconcileHealthService) updateHealthServiceStatus(original, h *operatorv1alpha1.HealthService, reconcileErr error) error {
+	reqLogger := log.WithValues("HealthService.Namespace", h.Namespace, "HealthService.Name", h.Name)
+
+	hsName := healthResourceName(h)
+	components := []componentStatus{
+		r.deploymentStatus(h.Namespace, memResourceName(h), "Memcached"),
+		r.deploymentStatus(h.Namespace, hsName, "HealthService"),
+		r.endpointsStatus(h.Namespace, hsName),
+		r.exposureStatus(h, hsName),
+	}
+
+	setStatusConditions(&h.Status.Conditions, &h.Status.Phase, h.Generation, components, reconcileErr)
+	setConfigValidCondition(&h.Status.Conditions, h.Generation, reconcileErr)
+
+	status := h.Status.DeepCopy()
+	err := common.PatchStatus(r.client, r.reader, original, h, func() {
+		original.DeepCopyInto(h)
+		status.DeepCopyInto(&h.Status)
+	})
+	if err != nil {
+		reqLogger.Error(err, "Failed to update HealthService status")
+		return err
+	}
+	return nil
+}
+
+// setStatusConditions sets the conditions and the phase from the component states
+func setStatusConditions(conditions *[]metav1.Condition, phase *operatorv1alpha1.Phase, generation int64,
+	components []componentStatus, reconcileErr error) {
+	ready := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "AllComponentsReady",
+		Message:            "All components are ready",
+	}
+	progressing := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionProgressing,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "RolloutComplete",
+		Message:            "All components are rolled out",
+	}
+	degraded := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "AsExpected",
+		Message:            "No component is degraded",
+	}
+
+	for _, c := range components {
+		if !c.ready && ready.Status == metav1.ConditionTrue {
+			ready.Status = metav1.ConditionFalse
+			ready.Reason = c.reason
+			ready.Message = c.message
+		}
+		if c.progressing && progressing.Status == metav1.ConditionFalse {
+			progressing.Status = metav1.ConditionTrue
+			progressing.Reason = c.reason
+			progressing.Message = c.message
+		}
+		if c.failed && degraded.Status == metav1.ConditionFalse {
+			degraded.Status = metav1.ConditionTrue
+			degraded.Reason = c.reason
+			degraded.Message = c.message
+		}
+	}
+
+	if reconcileErr != nil {
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = "ReconcileFailed"
+		degraded.Message = reconcileErr.Error()
+	}
+
+	meta.SetStatusCondition(conditions, ready)
+	meta.SetStatusCondition(conditions, progressing)
+	meta.SetStatusCondition(conditions, degraded)
+
+	switch {
+	case degraded.Status == metav1.ConditionTrue:
+		*phase = operatorv1alpha1.PhaseDegraded
+	case ready.Status == metav1.ConditionTrue:
+		*phase = operatorv1alpha1.PhaseRunning
+	default:
+		*phase = operatorv1alpha1.PhaseProgressing
+	}
+}
+
+// setConfigValidCondition sets the ConfigValid condition, false if reconcileErr is a configuration error
+func setConfigValidCondition(conditions *[]metav1.Condition, generation int64, reconcileErr error) {
+	configValid := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionConfigValid,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "ManifestLoaded",
+		Message:            "The cpnames and the CloudPaks are merged into the health service configmap",
+	}
+
+	var configErr *configError
+	if goerrors.As(reconcileErr, &configErr) {
+		configValid.Status = metav1.ConditionFalse
+		configValid.Reason = configErr.reason
+		configValid.Message = configErr.Error()
+	}
+
+	meta.SetStatusCondition(conditions, configValid)
+}
+
+func (r *ReconcileHealthService) deploymentStatus(namespace, name, component string) componentStatus {
+	dep := &appsv1.Deployment{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, dep)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return componentStatus{progressing: true, reason: component + "Pending",
+				message: fmt.Sprintf("deployment %q is not created yet", name)}
+		}
+		return componentStatus{failed: true, reason: component + "Unknown", message: err.Error()}
+	}
+
+	done, failed, message := common.DeploymentRolloutStatus(dep)
+	switch {
+	case failed:
+		return componentStatus{failed: true, reason: component + "RolloutFailed", message: message}
+	case !done:
+		return componentStatus{progressing: true, reason: component + "RollingOut", message: message}
+	}
+	return componentStatus{ready: true, reason: component + "Available", message: message}
+}
+
+func (r *ReconcileHealthService) endpointsStatus(namespace, name string) componentStatus {
+	ep := &corev1.Endpoints{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, ep)
+	if err != nil && !errors.IsNotFound(err) {
+		return componentStatus{failed: true, reason: "EndpointsUnknown", message: err.Error()}
+	}
+	if err != nil || !common.EndpointsReady(ep) {
+		return componentStatus{progressing: true, reason: "EndpointsNotReady",
+			message: fmt.Sprintf("service %q has no ready endpoints", name)}
+	}
+	return componentStatus{ready: true, reason: "EndpointsReady", message: fmt.Sprintf("service %q has ready endpoints", name)}
+}
+
+func (r *ReconcileHealthService) exposureStatus(h *operatorv1alpha1.HealthService, name string) componentStatus {
+	state := common.GetExposureState(r.client, r.mapper, &h.Spec.Exposure, &h.Spec.Ingress, name, h.Namespace)
+	return componentStatus{
+		ready:       state.Ready,
+		progressing: !state.Ready && !state.Failed,
+		failed:      state.Failed,
+		reason:      state.Reason,
+		message:     state.Message,
+	}
+}
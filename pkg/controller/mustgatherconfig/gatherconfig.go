@@ -0,0 +1,144 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	"github.com/IBM/ibm-healthcheck-operator/pkg/redact"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// gatherConfigKey is the configmap key mounted at /usr/bin/gather_config in the must gather jobs
+const gatherConfigKey = "gather_config"
+
+// gatherModules is the catalog of the modules of the must gather image
+var gatherModules = []string{"overview", "system", "failure", "ocp", "cloudpak"}
+
+// rawModulesPattern matches the modules setting of a raw gather config
+var rawModulesPattern = regexp.MustCompile(`(?m)^\s*modules=["']?([^"'\n]*)["']?\s*$`)
+
+// configError is an invalid setting of a MustGatherConfig, reported with the Valid condition
+type configError struct {
+	reason string
+	err    error
+}
+
+func (e *configError) Error() string {
+	return e.err.Error()
+}
+
+// renderGatherConfig returns the gather_config of the spec: the raw gather config followed by the
+// settings of the typed fields, which override the raw ones when the file is sourced
+func renderGatherConfig(spec *operatorv1alpha1.MustGatherConfigSpec) (string, error) {
+	if err := validateGatherConfig(spec); err != nil {
+		return "", err
+	}
+
+	lines := []string{}
+	if len(spec.Modules) > 0 {
+		lines = append(lines, shellAssignment("modules", strings.Join(spec.Modules, ",")))
+	}
+	if len(spec.Namespaces) > 0 {
+		lines = append(lines, shellAssignment("namespaces", strings.Join(spec.Namespaces, ",")))
+	}
+	if spec.LabelSelector != nil {
+		selector, _ := metav1.LabelSelectorAsSelector(spec.LabelSelector)
+		lines = append(lines, shellAssignment("labels", selector.String()))
+	}
+	// the raw gather config is kept as is when there is no typed field
+	if len(lines) == 0 {
+		return spec.GatherConfig, nil
+	}
+	if raw := strings.TrimRight(spec.GatherConfig, "\n"); raw != "" {
+		lines = append([]string{raw}, lines...)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// validateGatherConfig checks the modules, of the typed field or else of the raw gather config,
+// against the catalog, and the namespaces, the label selector and the redaction
+func validateGatherConfig(spec *operatorv1alpha1.MustGatherConfigSpec) error {
+	modules := spec.Modules
+	if len(modules) == 0 {
+		if m := rawModulesPattern.FindStringSubmatch(spec.GatherConfig); m != nil {
+			modules = strings.Split(m[1], ",")
+		}
+	}
+	unknown := []string{}
+	for _, module := range modules {
+		if module = strings.TrimSpace(module); module != "" && !knownModule(module) {
+			unknown = append(unknown, module)
+		}
+	}
+	if len(unknown) > 0 {
+		return &configError{"UnknownModule", fmt.Errorf("unknown modules %s, the modules are %s",
+			strings.Join(unknown, ","), strings.Join(gatherModules, ","))}
+	}
+
+	for _, ns := range spec.Namespaces {
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			return &configError{"InvalidNamespace", fmt.Errorf("invalid namespace %q: %s", ns, strings.Join(errs, ", "))}
+		}
+	}
+
+	if spec.LabelSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(spec.LabelSelector); err != nil {
+			return &configError{"InvalidLabelSelector", fmt.Errorf("invalid label selector: %v", err)}
+		}
+	}
+
+	if spec.Redaction != nil {
+		if _, err := redact.New(spec.Redaction); err != nil {
+			return &configError{"InvalidRedaction", err}
+		}
+	}
+	return nil
+}
+
+// renderRedactionPolicy returns the redaction policy of the spec as JSON, empty when there is none
+func renderRedactionPolicy(spec *operatorv1alpha1.MustGatherConfigSpec) (string, error) {
+	if spec.Redaction == nil {
+		return "", nil
+	}
+	policy, err := json.Marshal(spec.Redaction)
+	if err != nil {
+		return "", err
+	}
+	return string(policy), nil
+}
+
+func knownModule(module string) bool {
+	for _, m := range gatherModules {
+		if m == module {
+			return true
+		}
+	}
+	return false
+}
+
+// shellAssignment returns the shell assignment of value to name, value is double quoted
+func shellAssignment(name, value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(value)
+	return fmt.Sprintf("%s=\"%s\"", name, escaped)
+}
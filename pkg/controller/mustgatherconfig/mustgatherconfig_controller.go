@@ -18,14 +18,17 @@ package mustgatherconfig
 
 import (
 	"context"
+	goerrors "errors"
 
 	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
+	"github.com/IBM/ibm-healthcheck-operator/pkg/redact"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -51,7 +54,7 @@ func Add(mgr manager.Manager) error {
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileMustGatherConfig{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+	return &ReconcileMustGatherConfig{client: mgr.GetClient(), reader: mgr.GetAPIReader(), scheme: mgr.GetScheme()}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -68,9 +71,8 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
-	// TODO(user): Modify this to be the types you create that are owned by the primary resource
-	// Watch for changes to secondary resource Pods and requeue the owner MustGatherConfig
-	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestForOwner{
+	// Watch for changes to secondary resource ConfigMaps and requeue the owner MustGatherConfig
+	err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestForOwner{
 		IsController: true,
 		OwnerType:    &operatorv1alpha1.MustGatherConfig{},
 	})
@@ -89,6 +91,8 @@ type ReconcileMustGatherConfig struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
+	// reader reads from the apiserver, the status is patched from the latest object read with it
+	reader client.Reader
 	scheme *runtime.Scheme
 }
 
@@ -117,67 +121,144 @@ func (r *ReconcileMustGatherConfig) Reconcile(request reconcile.Request) (reconc
 		return reconcile.Result{}, err
 	}
 
+	// The status is gathered in instance and written once, as a patch from original
+	original := instance.DeepCopy()
+
+	// The configmap is labeled as the objects of its must gather service
+	appName, err := r.mustGatherServiceAppName(instance)
+	var configErr *configError
+	if err != nil && !goerrors.As(err, &configErr) {
+		return reconcile.Result{}, err
+	}
+
+	// Render the gather config, an invalid config leaves the configmap as is until the spec is fixed
+	gatherConfig := ""
+	if err == nil {
+		gatherConfig, err = renderGatherConfig(&instance.Spec)
+	}
+	setValidCondition(&instance.Status.Conditions, instance.Generation, err)
+	if err != nil {
+		reqLogger.Error(err, "Invalid MustGatherConfig")
+		return reconcile.Result{}, r.updateMustGatherConfigStatus(original, instance)
+	}
+
+	redactionPolicy, err := renderRedactionPolicy(&instance.Spec)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
 	// Define a new Config object
-	configmap := newMustGatherConfig(instance)
+	configmap := newMustGatherConfig(instance, appName, gatherConfig, redactionPolicy)
 
 	// Set MustGatherConfig instance as the owner and controller
 	if err := controllerutil.SetControllerReference(instance, configmap, r.scheme); err != nil {
 		return reconcile.Result{}, err
 	}
 
-	// Check if this Configmap already exists
+	// Create the configmap, or update it if it drifted from the config
 	found := &corev1.ConfigMap{}
-	err = r.client.Get(context.TODO(), types.NamespacedName{Name: configmap.Name, Namespace: configmap.Namespace}, found)
-	if err != nil && errors.IsNotFound(err) {
-		reqLogger.Info("Creating a new configmap", "configmap.Namespace", configmap.Namespace, "configmap.Name", configmap.Name)
-		err = r.client.Create(context.TODO(), configmap)
-		if err != nil {
-			return reconcile.Result{}, err
-		}
-		// Pod created successfully - don't requeue
-		return reconcile.Result{}, nil
-	} else if err != nil {
-		return reconcile.Result{}, err
-	} else if err := r.updateMustGatherConfigMap(instance, found, configmap); err != nil {
+	result, err := common.CreateOrUpdate(r.client, instance, configmap, found, func() runtime.Object {
+		return r.updatedMustGatherConfigMap(instance, found, configmap)
+	})
+	if err != nil {
 		return reconcile.Result{}, err
 	}
+	if result != controllerutil.OperationResultNone {
+		reqLogger.Info("configmap "+string(result), "configmap.Namespace", configmap.Namespace, "configmap.Name", configmap.Name)
+	}
+
+	return reconcile.Result{}, r.updateMustGatherConfigStatus(original, instance)
+}
+
+// mustGatherServiceAppName returns the app name of the objects of the MustGatherService of the config,
+// else the legacy name. An invalid MustGatherService reference is a configError.
+func (r *ReconcileMustGatherConfig) mustGatherServiceAppName(instance *operatorv1alpha1.MustGatherConfig) (string, error) {
+	mgs, err := common.SelectMustGatherService(r.client, instance.Namespace, instance.Spec.MustGatherServiceName)
+	var refErr *common.ReferenceError
+	if goerrors.As(err, &refErr) {
+		return "", &configError{refErr.Reason, refErr}
+	}
+	if err != nil {
+		return "", err
+	}
+	if mgs == nil {
+		return common.LegacyMustGatherResourceName, nil
+	}
+	return common.MustGatherResourceName(mgs), nil
+}
 
-	return reconcile.Result{}, nil
+// setValidCondition sets the Valid condition, false if err is a configError
+func setValidCondition(conditions *[]metav1.Condition, generation int64, err error) {
+	valid := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionValid,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "ConfigRendered",
+		Message:            "The gather config is rendered into the configmap",
+	}
+	var configErr *configError
+	if goerrors.As(err, &configErr) {
+		valid.Status = metav1.ConditionFalse
+		valid.Reason = configErr.reason
+		valid.Message = configErr.Error()
+	}
+	meta.SetStatusCondition(conditions, valid)
 }
 
-func (r *ReconcileMustGatherConfig) updateMustGatherConfigMap(instance *operatorv1alpha1.MustGatherConfig,
-	current, desired *corev1.ConfigMap) error {
+// updateMustGatherConfigStatus writes the status gathered during the reconcile once, as a patch from original
+func (r *ReconcileMustGatherConfig) updateMustGatherConfigStatus(original, instance *operatorv1alpha1.MustGatherConfig) error {
+	reqLogger := log.WithValues("MustGatherConfig.Namespace", instance.Namespace, "MustGatherConfig.Name", instance.Name)
+
+	status := instance.Status.DeepCopy()
+	err := common.PatchStatus(r.client, r.reader, original, instance, func() {
+		original.DeepCopyInto(instance)
+		status.DeepCopyInto(&instance.Status)
+	})
+	if err != nil {
+		reqLogger.Error(err, "Failed to update MustGatherConfig status")
+		return err
+	}
+	return nil
+}
+
+// updatedMustGatherConfigMap returns a copy of current in which the gather config and labels are set from desired
+func (r *ReconcileMustGatherConfig) updatedMustGatherConfigMap(instance *operatorv1alpha1.MustGatherConfig,
+	current, desired *corev1.ConfigMap) *corev1.ConfigMap {
 	reqLogger := log.WithValues("ConfigMap.Namespace", current.Namespace, "ConfigMap.Name", current.Name)
 
 	updated := current.DeepCopy()
 	updated.Data = desired.Data
+	if updated.Labels == nil {
+		updated.Labels = map[string]string{}
+	}
+	for k, v := range desired.Labels {
+		updated.Labels[k] = v
+	}
 
-	reqLogger.Info("Updating ConfigMap")
 	// Set MustGatherConfig instance as the owner and controller
 	if err := controllerutil.SetControllerReference(instance, updated, r.scheme); err != nil {
 		reqLogger.Error(err, "SetControllerReference failed", "ConfigMap.Namespace", updated.Namespace, "ConfigMap.Name", updated.Name)
 	}
 
-	if err := r.client.Update(context.TODO(), updated); err != nil {
-		reqLogger.Error(err, "Failed to update ConfigMap", "ConfigMap.Namespace", updated.Namespace, "ConfigMap.Name", updated.Name)
-		return err
-	}
-
-	return nil
+	return updated
 }
 
-// newMustGatherConfig returns a configmap
-func newMustGatherConfig(cr *operatorv1alpha1.MustGatherConfig) *corev1.ConfigMap {
+// newMustGatherConfig returns a configmap with the rendered gather config, and the redaction policy
+// applied by the must gather jobs once the gather succeeds, labeled for the must gather service appName
+func newMustGatherConfig(cr *operatorv1alpha1.MustGatherConfig, appName, gatherConfig, redactionPolicy string) *corev1.ConfigMap {
 	configMapData := make(map[string]string)
 
 	// load config file
-	configMapData["gather_config"] = cr.Spec.GatherConfig
+	configMapData[gatherConfigKey] = gatherConfig
+	if redactionPolicy != "" {
+		configMapData[redact.PolicyKey] = redactionPolicy
+	}
 
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        cr.Name,
 			Namespace:   cr.Namespace,
-			Labels:      labelsForMustGatherConfig("must-gather-service", cr.Name),
+			Labels:      labelsForMustGatherConfig(appName, cr.Name),
 			Annotations: annotationsForMustGatherConfig(),
 		},
 		Data: configMapData,
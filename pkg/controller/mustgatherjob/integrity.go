@@ -0,0 +1,130 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherjob
+
+import (
+	"context"
+	"path"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
+	"github.com/IBM/ibm-healthcheck-operator/pkg/integrity"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// signingKeyPath is where the signing key secret is mounted in the signing pod
+const signingKeyPath = "/etc/must-gather-signing"
+
+// reconcileIntegrity creates the signing job once the gather job succeeded and its data is redacted, when
+// the cr sets integrity, and records the digest of the archive and the state of its signed manifest
+func (r *ReconcileMustGatherJob) reconcileIntegrity(cr *operatorv1alpha1.MustGatherJob, gatherJob *batchv1.Job) error {
+	reqLogger := log.WithValues("MustGatherJob.Namespace", cr.Namespace, "MustGatherJob.Name", cr.Name)
+
+	spec := cr.Spec.Integrity
+	if spec == nil || common.JobCondition(gatherJob, batchv1.JobComplete) == nil || !redactionReady(&cr.Status) {
+		setIntegrityStatus(&cr.Status, cr.Generation, spec, gatherJob, nil, nil)
+		return nil
+	}
+
+	job := &batchv1.Job{}
+	name := postGatherJobName(gatherJob.Name, "-sign")
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: cr.Namespace}, job)
+	if err != nil && errors.IsNotFound(err) {
+		image, pullPolicy, err := common.OperatorImage(r.client)
+		if err != nil {
+			reqLogger.Error(err, "Failed to get the operator image")
+			return err
+		}
+		job = newSigningJob(cr, gatherJob, gatherClaimName(gatherJob), spec, name, image, pullPolicy)
+		// Pull the images from their registry mirror
+		if err := common.SetMirroredImages(r.client, &job.Spec.Template); err != nil {
+			return err
+		}
+		if err := controllerutil.SetControllerReference(cr, job, r.scheme); err != nil {
+			return err
+		}
+		reqLogger.Info("Creating a new signing job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		if err := r.client.Create(context.TODO(), job); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	pod, err := common.LatestJobPod(r.client, job)
+	if err != nil {
+		return err
+	}
+	setIntegrityStatus(&cr.Status, cr.Generation, spec, gatherJob, job, pod)
+	return nil
+}
+
+// integrityReady returns whether the gathered data can leave the must gather volume: its manifest is
+// signed, or there is no manifest
+func integrityReady(status *operatorv1alpha1.MustGatherJobStatus) bool {
+	return status.Integrity == nil || status.Integrity.Phase == operatorv1alpha1.JobSucceeded
+}
+
+// newSigningJob returns the job which archives the data of the gather job on the claim and signs the
+// manifest of the archive, with the sign command of the operator binary and the key of the signing key secret
+func newSigningJob(cr *operatorv1alpha1.MustGatherJob, gatherJob *batchv1.Job, claimName string,
+	spec *operatorv1alpha1.Integrity, name, image string, pullPolicy corev1.PullPolicy) *batchv1.Job {
+	gather := gatherJob.Spec.Template.Spec.Containers[0]
+
+	container := corev1.Container{
+		Name:            "sign",
+		Image:           image,
+		ImagePullPolicy: pullPolicy,
+		Command: []string{
+			operatorBinary, "sign",
+			"--key", path.Join(signingKeyPath, integrity.PrivateKeySecretKey),
+			"--artifact", gatherJob.Name,
+			mustGatherDataPath,
+		},
+		Resources:       gather.Resources,
+		SecurityContext: gather.SecurityContext,
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "must-gather-pvc",
+				MountPath: mustGatherDataPath,
+			},
+			{
+				Name:      "signing-key",
+				MountPath: signingKeyPath,
+				ReadOnly:  true,
+			},
+		},
+	}
+	volumes := []corev1.Volume{
+		{
+			Name: "signing-key",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: spec.SigningKeySecretName,
+					Items:      []corev1.KeyToPath{{Key: integrity.PrivateKeySecretKey, Path: integrity.PrivateKeySecretKey}},
+				},
+			},
+		},
+	}
+
+	return newPostGatherJob(cr, gatherJob, claimName, name, "must-gather-sign", container, volumes)
+}
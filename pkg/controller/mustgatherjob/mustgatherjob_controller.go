@@ -18,10 +18,13 @@ package mustgatherjob
 
 import (
 	"context"
-	"os"
+	goerrors "errors"
+	"fmt"
 	"strings"
 
 	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
+	constant "github.com/IBM/ibm-healthcheck-operator/pkg/controller/constant"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -29,6 +32,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -41,6 +45,32 @@ import (
 
 var log = logf.Log.WithName("controller_mustgatherjob")
 
+// defaultMustGatherPVCName is the claim the gathered data is written to when there is no MustGatherService
+const defaultMustGatherPVCName = "must-gather-pvc"
+
+// specError is an invalid spec of the MustGatherJob, it is reported in the status until the spec is fixed
+type specError struct {
+	err error
+}
+
+func (e *specError) Error() string {
+	return e.err.Error()
+}
+
+// defaultTolerations are the tolerations of the must gather service pod, so that the job pod
+// can run on its node. A custom toleration with the same key and effect replaces the default one.
+var defaultTolerations = []corev1.Toleration{
+	{
+		Key:      "dedicated",
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffectNoSchedule,
+	},
+	{
+		Key:      "CriticalAddonsOnly",
+		Operator: corev1.TolerationOpExists,
+	},
+}
+
 /**
 * USER ACTION REQUIRED: This is a scaffold file intended for the user to modify with their own Controller
 * business logic.  Delete these comments after modifying this file.*
@@ -54,7 +84,7 @@ func Add(mgr manager.Manager) error {
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileMustGatherJob{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+	return &ReconcileMustGatherJob{client: mgr.GetClient(), reader: mgr.GetAPIReader(), scheme: mgr.GetScheme()}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -71,9 +101,8 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
-	// TODO(user): Modify this to be the types you create that are owned by the primary resource
-	// Watch for changes to secondary resource Pods and requeue the owner MustGatherJob
-	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestForOwner{
+	// Watch for changes to secondary resource Jobs and requeue the owner MustGatherJob
+	err = c.Watch(&source.Kind{Type: &batchv1.Job{}}, &handler.EnqueueRequestForOwner{
 		IsController: true,
 		OwnerType:    &operatorv1alpha1.MustGatherJob{},
 	})
@@ -81,6 +110,15 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Pods are owned by the Job, they are mapped to the MustGatherJob which owns the Job
+	// so that the pod name and the running phase are refreshed
+	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: common.OperandPodRequests(mgr.GetClient(), "MustGatherJob"),
+	})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -92,6 +130,8 @@ type ReconcileMustGatherJob struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
+	// reader reads from the apiserver, the status is patched from the latest object read with it
+	reader client.Reader
 	scheme *runtime.Scheme
 }
 
@@ -120,37 +160,247 @@ func (r *ReconcileMustGatherJob) Reconcile(request reconcile.Request) (reconcile
 		return reconcile.Result{}, err
 	}
 
-	// Define a new must gather job
-	job := newMustGatherJob(instance)
+	// The status is gathered in instance and written once, as a patch from original
+	original := instance.DeepCopy()
 
-	// Set MustGatherJob instance as the owner and controller
-	if err := controllerutil.SetControllerReference(instance, job, r.scheme); err != nil {
+	if instance.Spec.Cancel {
+		if err := r.cancelMustGatherJob(instance); err != nil {
+			reqLogger.Error(err, "Failed to cancel must gather job")
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, r.updateMustGatherJobStatus(original, instance, nil)
+	}
+
+	// Every generation of the spec is a new run, with its own job
+	jobName, err := r.currentJobName(instance)
+	if err != nil {
 		return reconcile.Result{}, err
 	}
+	if jobName == "" {
+		if err := r.deletePreviousRunJobs(instance); err != nil {
+			reqLogger.Error(err, "Failed to delete the jobs of the previous run", "Job.Name", instance.Status.JobName)
+			return reconcile.Result{}, err
+		}
+		jobName = mustGatherJobName(instance)
+	}
 
-	// Check if this Pod already exists
+	// Check if this Job already exists
 	found := &batchv1.Job{}
-	err = r.client.Get(context.TODO(), types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, found)
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: jobName, Namespace: instance.Namespace}, found)
 	if err != nil && errors.IsNotFound(err) {
+		// Define a new must gather job
+		job, err := r.desiredMustGatherJob(instance, jobName)
+		var refErr *common.ReferenceError
+		var specErr *specError
+		if goerrors.As(err, &refErr) || goerrors.As(err, &specErr) {
+			// the request is retried when the spec is fixed
+			reqLogger.Error(err, "Invalid MustGatherJob")
+			instance.Status.ObservedGeneration = instance.Generation
+			setInvalidSpecStatus(&instance.Status, instance.Generation, err)
+			return reconcile.Result{}, r.updateMustGatherJobStatus(original, instance, nil)
+		}
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+
 		reqLogger.Info("Creating a new must gahter job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
 		err = r.client.Create(context.TODO(), job)
 		if err != nil {
 			return reconcile.Result{}, err
 		}
-
-		// Pod created successfully - don't requeue
-		return reconcile.Result{}, nil
+		found = job
 	} else if err != nil {
 		return reconcile.Result{}, err
 	}
 
-	// Job already exists - don't requeue
-	reqLogger.Info("Skip reconcile: Jod already exists", "Job.Namespace", found.Namespace, "Job.Name", found.Name)
+	// The gathered data is redacted, signed, then uploaded, by other jobs once the gather succeeded
+	if err := r.reconcileRedaction(instance, found); err != nil {
+		reqLogger.Error(err, "Failed to redact the gathered data")
+		return reconcile.Result{}, err
+	}
+	if err := r.reconcileIntegrity(instance, found); err != nil {
+		reqLogger.Error(err, "Failed to sign the manifest of the gathered data")
+		return reconcile.Result{}, err
+	}
+	if err := r.reconcileUpload(instance, found); err != nil {
+		reqLogger.Error(err, "Failed to upload the gathered data")
+		return reconcile.Result{}, err
+	}
+
+	// The job is not updated once created, its progress is reported in the MustGatherJob status
+	instance.Status.ObservedGeneration = instance.Generation
+	if err := r.updateMustGatherJobStatus(original, instance, found); err != nil {
+		return reconcile.Result{}, err
+	}
 	return reconcile.Result{}, nil
 }
 
-// newMustGatherJob returns job with the same name/namespace as the cr
-func newMustGatherJob(cr *operatorv1alpha1.MustGatherJob) *batchv1.Job {
+// currentJobName returns the name of the job of the current run, empty when the spec changed since
+// the job was created, or when the gather never ran. The job of a MustGatherJob created before runs
+// were tracked is named after the MustGatherJob, it is the current run.
+func (r *ReconcileMustGatherJob) currentJobName(cr *operatorv1alpha1.MustGatherJob) (string, error) {
+	if cr.Status.ObservedGeneration == 0 {
+		legacy := &batchv1.Job{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, legacy)
+		if err == nil && metav1.IsControlledBy(legacy, cr) {
+			return cr.Name, nil
+		}
+		if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+		return "", nil
+	}
+	if cr.Status.ObservedGeneration != cr.Generation || cr.Status.Phase == operatorv1alpha1.JobCancelled {
+		return "", nil
+	}
+	return cr.Status.JobName, nil
+}
+
+// cancelMustGatherJob deletes the job of the current run when it is not finished, and records the cancellation
+func (r *ReconcileMustGatherJob) cancelMustGatherJob(cr *operatorv1alpha1.MustGatherJob) error {
+	cr.Status.ObservedGeneration = cr.Generation
+	switch cr.Status.Phase {
+	case operatorv1alpha1.JobSucceeded, operatorv1alpha1.JobFailed, operatorv1alpha1.JobCancelled:
+		return nil
+	}
+
+	if err := r.deleteActiveJob(cr, cr.Status.JobName); err != nil {
+		return err
+	}
+	setCancelledStatus(&cr.Status, cr.Generation)
+	return nil
+}
+
+// deleteActiveJob deletes the job with the pods when it is not finished
+func (r *ReconcileMustGatherJob) deleteActiveJob(cr *operatorv1alpha1.MustGatherJob, name string) error {
+	if name == "" {
+		return nil
+	}
+	job := &batchv1.Job{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: cr.Namespace}, job)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !metav1.IsControlledBy(job, cr) || common.JobFinished(job) {
+		return nil
+	}
+
+	log.Info("Deleting must gather job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+	err = r.client.Delete(context.TODO(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// deletePreviousRunJobs deletes the jobs of the cr, which are the gather, redaction, signing and upload
+// jobs of the previous run when a new run starts. The gathered data on the volume is kept.
+func (r *ReconcileMustGatherJob) deletePreviousRunJobs(cr *operatorv1alpha1.MustGatherJob) error {
+	jobs := &batchv1.JobList{}
+	if err := r.client.List(context.TODO(), jobs, client.InNamespace(cr.Namespace)); err != nil {
+		return err
+	}
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if !metav1.IsControlledBy(job, cr) {
+			continue
+		}
+		log.Info("Deleting must gather job of the previous run", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		err := r.client.Delete(context.TODO(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// desiredMustGatherJob returns the job named name of the cr, which writes to the volume of the selected
+// MustGatherService. An invalid spec or MustGatherService reference is an error the status reports.
+func (r *ReconcileMustGatherJob) desiredMustGatherJob(cr *operatorv1alpha1.MustGatherJob, name string) (*batchv1.Job, error) {
+	mgs, err := common.SelectMustGatherService(r.client, cr.Namespace, cr.Spec.MustGatherServiceName)
+	if err != nil {
+		return nil, err
+	}
+	claimName := defaultMustGatherPVCName
+	if mgs != nil {
+		claimName = mgs.Spec.PersistentVolumeClaim.Name
+	}
+
+	// The job pod only has to run next to the must gather service when the volume is not shared
+	serviceLabels, err := r.mustGatherServicePodLabels(mgs, claimName)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := newMustGatherJob(cr, name, claimName, serviceLabels)
+	if err != nil {
+		return nil, &specError{err}
+	}
+	// Pull the images from their registry mirror
+	if err := common.SetMirroredImages(r.client, &job.Spec.Template); err != nil {
+		log.Error(err, "Failed to get the registry mirrors")
+		return nil, err
+	}
+	// Record the gather config the job runs with
+	if err := common.SetConfigHash(r.client, cr.Namespace, &job.Spec.Template); err != nil {
+		return nil, err
+	}
+
+	// Set MustGatherJob instance as the owner and controller
+	if err := controllerutil.SetControllerReference(cr, job, r.scheme); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// sharedMustGatherVolume returns whether the must gather PVC can be mounted by pods on any node
+func (r *ReconcileMustGatherJob) sharedMustGatherVolume(namespace, claimName string) (bool, error) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: claimName, Namespace: namespace}, pvc)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, mode := range pvc.Spec.AccessModes {
+		if mode == corev1.ReadWriteMany {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// mustGatherServicePodLabels returns the labels of the pod of the MustGatherService, which mounts its
+// claim. It is nil when the volume is shared or there is no MustGatherService.
+func (r *ReconcileMustGatherJob) mustGatherServicePodLabels(mgs *operatorv1alpha1.MustGatherService, claimName string) (map[string]string, error) {
+	if mgs == nil {
+		return nil, nil
+	}
+	sharedVolume, err := r.sharedMustGatherVolume(mgs.Namespace, claimName)
+	if err != nil || sharedVolume {
+		return nil, err
+	}
+	return common.LabelsForMustGatherService(common.MustGatherResourceName(mgs), mgs.Name), nil
+}
+
+// mustGatherJobName returns the name of the job of the current generation of the MustGatherJob
+func mustGatherJobName(cr *operatorv1alpha1.MustGatherJob) string {
+	suffix := fmt.Sprintf("-%d", cr.Generation)
+	name := cr.Name
+	// the job name is a pod label value, of at most 63 characters
+	if len(name)+len(suffix) > validation.LabelValueMaxLength {
+		name = name[:validation.LabelValueMaxLength-len(suffix)]
+	}
+	return strings.TrimRight(name, "-.") + suffix
+}
+
+// newMustGatherJob returns the job named name of the cr, in the same namespace, which writes to the claim.
+// When the labels of the must gather service pod are set, the job pod prefers its node, as the pod mounts the volume.
+func newMustGatherJob(cr *operatorv1alpha1.MustGatherJob, name, claimName string, serviceLabels map[string]string) (*batchv1.Job, error) {
 	var backoffLimit = int32(4)
 
 	appName := cr.Name
@@ -160,16 +410,16 @@ func newMustGatherJob(cr *operatorv1alpha1.MustGatherJob) *batchv1.Job {
 		serviceAccountName = cr.Spec.ServiceAccountName
 	}
 
-	image := os.Getenv("MUST_GATHER_IMAGE")
+	image, pullPolicy := common.ResolveImage(&cr.Spec.Image, constant.MustGatherImageEnv, constant.DefaultMustGatherImage)
 
-	command := []string{"gather"}
-	if len(cr.Spec.MustGatherCommand) > 0 {
-		command = strings.Split(cr.Spec.MustGatherCommand, " ")
+	command, err := mustGatherCommand(&cr.Spec)
+	if err != nil {
+		return nil, err
 	}
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      appName,
+			Name:      name,
 			Namespace: cr.Namespace,
 			Labels:    labelsForMustGatherJob("must-gather-job", cr.Name),
 		},
@@ -184,28 +434,18 @@ func newMustGatherJob(cr *operatorv1alpha1.MustGatherJob) *batchv1.Job {
 				Spec: corev1.PodSpec{
 					RestartPolicy:      "Never",
 					ServiceAccountName: serviceAccountName,
-					Affinity: &corev1.Affinity{
-						PodAffinity: &corev1.PodAffinity{
-							RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
-								{
-									LabelSelector: &metav1.LabelSelector{
-										MatchLabels: map[string]string{
-											"app.kubernetes.io/name":       "must-gather-service",
-											"app.kubernetes.io/instance":   "must-gather-service",
-											"app.kubernetes.io/managed-by": "",
-										},
-									},
-									TopologyKey: "kubernetes.io/hostname",
-								},
-							},
-						},
-					},
+					NodeSelector:       cr.Spec.NodeSelector,
+					Tolerations:        common.MergeTolerations(defaultTolerations, cr.Spec.Tolerations),
+					ImagePullSecrets:   cr.Spec.ImagePullSecrets,
 					Containers: []corev1.Container{
 						{
 							Name:            appName,
 							Image:           image,
-							ImagePullPolicy: corev1.PullPolicy(cr.Spec.Image.PullPolicy),
+							ImagePullPolicy: pullPolicy,
 							Command:         command,
+							Args:            cr.Spec.Args,
+							Resources:       *cr.Spec.Resources.DeepCopy(),
+							SecurityContext: common.MergeSecurityContext(&corev1.SecurityContext{}, &cr.Spec.SecurityContext),
 							Env: []corev1.EnvVar{
 								{
 									Name:  "FROM_OPERATOR",
@@ -213,7 +453,7 @@ func newMustGatherJob(cr *operatorv1alpha1.MustGatherJob) *batchv1.Job {
 								},
 								{
 									Name:  "INSTANCE_NAME",
-									Value: appName,
+									Value: name,
 								},
 							},
 							VolumeMounts: []corev1.VolumeMount{
@@ -224,22 +464,32 @@ func newMustGatherJob(cr *operatorv1alpha1.MustGatherJob) *batchv1.Job {
 							},
 						},
 					},
-					Volumes: []corev1.Volume{
-						{
-							Name: "must-gather-pvc",
-							VolumeSource: corev1.VolumeSource{
-								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-									ClaimName: "must-gather-pvc",
-								},
+					Volumes: []corev1.Volume{mustGatherVolume(claimName)},
+				},
+			},
+		},
+	}
+
+	if serviceLabels != nil {
+		job.Spec.Template.Spec.Affinity = &corev1.Affinity{
+			PodAffinity: &corev1.PodAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+					{
+						Weight: 100,
+						PodAffinityTerm: corev1.PodAffinityTerm{
+							LabelSelector: &metav1.LabelSelector{
+								MatchLabels: serviceLabels,
 							},
+							TopologyKey: "kubernetes.io/hostname",
 						},
 					},
 				},
 			},
-		},
+		}
 	}
 
-	if len(cr.Spec.MustGatherCommand) == 0 {
+	// the gather config is mounted for any command, which may source it
+	if len(cr.Spec.MustGatherConfigName) > 0 {
 		job.Spec.Template.Spec.Containers[0].VolumeMounts = append(job.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
 			Name:      "must-gather-config",
 			MountPath: "/usr/bin/gather_config",
@@ -257,7 +507,73 @@ func newMustGatherJob(cr *operatorv1alpha1.MustGatherJob) *batchv1.Job {
 		})
 	}
 
-	return job
+	return job, nil
+}
+
+// newPostGatherJob returns the job named name which runs container on the gathered data of the gather
+// job of the cr, on the claim. Its pod is scheduled as the gather pod, with the volumes only.
+func newPostGatherJob(cr *operatorv1alpha1.MustGatherJob, gatherJob *batchv1.Job, claimName, name, app string,
+	container corev1.Container, volumes []corev1.Volume) *batchv1.Job {
+	var backoffLimit = int32(2)
+
+	template := gatherJob.Spec.Template.DeepCopy()
+	// the labels the job controller added to the gather pod template select the gather pod
+	template.ObjectMeta = metav1.ObjectMeta{
+		Labels:      labelsForMustGatherJob(app, cr.Name),
+		Annotations: annotationsForMustGatherJob(),
+	}
+	template.Spec.Containers = []corev1.Container{container}
+	template.Spec.Volumes = append([]corev1.Volume{mustGatherVolume(claimName)}, volumes...)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: gatherJob.Namespace,
+			Labels:    labelsForMustGatherJob(app, cr.Name),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template:     *template,
+		},
+	}
+}
+
+// mustGatherVolume returns the must-gather-pvc volume of the claim the gathered data is written to
+func mustGatherVolume(claimName string) corev1.Volume {
+	return corev1.Volume{
+		Name: "must-gather-pvc",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: claimName,
+			},
+		},
+	}
+}
+
+// gatherClaimName returns the claim the gather job wrote its data to
+func gatherClaimName(gatherJob *batchv1.Job) string {
+	for _, v := range gatherJob.Spec.Template.Spec.Volumes {
+		if v.Name == "must-gather-pvc" && v.PersistentVolumeClaim != nil {
+			return v.PersistentVolumeClaim.ClaimName
+		}
+	}
+	return defaultMustGatherPVCName
+}
+
+// mustGatherCommand returns the command of the spec, else the words of the legacy command string,
+// else the default gather command
+func mustGatherCommand(spec *operatorv1alpha1.MustGatherJobSpec) ([]string, error) {
+	if len(spec.Command) > 0 {
+		return spec.Command, nil
+	}
+	if strings.TrimSpace(spec.MustGatherCommand) == "" {
+		return []string{"gather"}, nil
+	}
+	command, err := splitShellWords(spec.MustGatherCommand)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mustgatherCommand: %v", err)
+	}
+	return command, nil
 }
 
 func labelsForMustGatherJob(name string, releaseName string) map[string]string {
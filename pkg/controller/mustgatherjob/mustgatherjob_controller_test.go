@@ -0,0 +1,78 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherjob
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDeletePreviousRunJobs(t *testing.T) {
+	newCR := func(name string) *operatorv1alpha1.MustGatherJob {
+		return &operatorv1alpha1.MustGatherJob{
+			TypeMeta:   metav1.TypeMeta{APIVersion: operatorv1alpha1.SchemeGroupVersion.String(), Kind: "MustGatherJob"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ibm-common-services", UID: types.UID(name + "-uid")},
+		}
+	}
+	cr := newCR("nightly")
+	other := newCR("weekly")
+	newJob := func(name string, owner *operatorv1alpha1.MustGatherJob) *batchv1.Job {
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ibm-common-services"}}
+		if owner != nil {
+			job.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, owner.GroupVersionKind())}
+		}
+		return job
+	}
+
+	s := runtime.NewScheme()
+	if err := batchv1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := operatorv1alpha1.SchemeBuilder.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewFakeClientWithScheme(s,
+		newJob("nightly-1", cr), newJob("nightly-1-redact", cr), newJob("nightly-1-sign", cr), newJob("nightly-1-upload", cr),
+		newJob("weekly-1", other), newJob("nightly-backup", nil))
+	r := &ReconcileMustGatherJob{client: c, reader: c, scheme: s}
+
+	if err := r.deletePreviousRunJobs(cr); err != nil {
+		t.Fatal(err)
+	}
+	jobs := &batchv1.JobList{}
+	if err := c.List(context.TODO(), jobs); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, job := range jobs.Items {
+		names = append(names, job.Name)
+	}
+	sort.Strings(names)
+	if want := []string{"nightly-backup", "weekly-1"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("jobs = %v, want %v", names, want)
+	}
+}
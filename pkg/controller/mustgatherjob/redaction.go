@@ -0,0 +1,175 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherjob
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
+	"github.com/IBM/ibm-healthcheck-operator/pkg/redact"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// operatorBinary is the operator binary in the operator image, its redact command redacts the gathered data
+	operatorBinary = "/usr/local/bin/ibm-healthcheck-operator"
+	// redactionPolicyPath is where the redaction policy of the must gather config is mounted in the redaction pod
+	redactionPolicyPath = "/etc/must-gather-redaction"
+)
+
+// reconcileRedaction creates the redaction job once the gather job succeeded, when the must gather config
+// has a redaction policy, and records the state and the counts of the redaction
+func (r *ReconcileMustGatherJob) reconcileRedaction(cr *operatorv1alpha1.MustGatherJob, gatherJob *batchv1.Job) error {
+	reqLogger := log.WithValues("MustGatherJob.Namespace", cr.Namespace, "MustGatherJob.Name", cr.Name)
+
+	policy, err := r.hasRedactionPolicy(cr)
+	if err != nil {
+		return err
+	}
+	if !policy || common.JobCondition(gatherJob, batchv1.JobComplete) == nil {
+		setRedactionStatus(&cr.Status, cr.Generation, policy, gatherJob, nil, nil)
+		return nil
+	}
+
+	job := &batchv1.Job{}
+	name := postGatherJobName(gatherJob.Name, "-redact")
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: cr.Namespace}, job)
+	if err != nil && errors.IsNotFound(err) {
+		image, pullPolicy, err := common.OperatorImage(r.client)
+		if err != nil {
+			reqLogger.Error(err, "Failed to get the operator image")
+			return err
+		}
+		job = newRedactionJob(cr, gatherJob, gatherClaimName(gatherJob), name, image, pullPolicy)
+		// Pull the images from their registry mirror
+		if err := common.SetMirroredImages(r.client, &job.Spec.Template); err != nil {
+			return err
+		}
+		if err := controllerutil.SetControllerReference(cr, job, r.scheme); err != nil {
+			return err
+		}
+		reqLogger.Info("Creating a new redaction job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		if err := r.client.Create(context.TODO(), job); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	pod, err := common.LatestJobPod(r.client, job)
+	if err != nil {
+		return err
+	}
+	setRedactionStatus(&cr.Status, cr.Generation, policy, gatherJob, job, pod)
+	return nil
+}
+
+// hasRedactionPolicy returns whether the configmap of the must gather config of the cr has a redaction policy
+func (r *ReconcileMustGatherJob) hasRedactionPolicy(cr *operatorv1alpha1.MustGatherJob) (bool, error) {
+	if cr.Spec.MustGatherConfigName == "" {
+		return false, nil
+	}
+	cm := &corev1.ConfigMap{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: cr.Spec.MustGatherConfigName, Namespace: cr.Namespace}, cm)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	_, ok := cm.Data[redact.PolicyKey]
+	return ok, nil
+}
+
+// redactionReady returns whether the gathered data can leave the must gather volume: all its files are
+// redacted, or there is no redaction policy
+func redactionReady(status *operatorv1alpha1.MustGatherJobStatus) bool {
+	return status.Redaction == nil ||
+		(status.Redaction.Phase == operatorv1alpha1.JobSucceeded && status.Redaction.FilesSkipped == 0)
+}
+
+// redactionIncomplete returns whether the redaction succeeded but skipped files it can't redact
+func redactionIncomplete(status *operatorv1alpha1.MustGatherJobStatus) bool {
+	return status.Redaction != nil && status.Redaction.Phase == operatorv1alpha1.JobSucceeded &&
+		status.Redaction.FilesSkipped > 0
+}
+
+// postGatherJobName returns the name of the job with suffix which runs on the data of the gather job
+func postGatherJobName(gatherJobName, suffix string) string {
+	name := gatherJobName
+	// the job name is a pod label value, of at most 63 characters
+	if len(name)+len(suffix) > validation.LabelValueMaxLength {
+		name = name[:validation.LabelValueMaxLength-len(suffix)]
+	}
+	return strings.TrimRight(name, "-.") + suffix
+}
+
+// newRedactionJob returns the job which redacts the data of the gather job in place on the claim, with
+// the redact command of the operator binary and the redaction policy of the must gather config
+func newRedactionJob(cr *operatorv1alpha1.MustGatherJob, gatherJob *batchv1.Job, claimName, name, image string,
+	pullPolicy corev1.PullPolicy) *batchv1.Job {
+	gather := gatherJob.Spec.Template.Spec.Containers[0]
+
+	container := corev1.Container{
+		Name:            "redact",
+		Image:           image,
+		ImagePullPolicy: pullPolicy,
+		Command: []string{
+			operatorBinary, "redact",
+			"--policy", path.Join(redactionPolicyPath, redact.PolicyKey),
+			"--artifact", gatherJob.Name,
+			mustGatherDataPath,
+		},
+		Resources:       gather.Resources,
+		SecurityContext: gather.SecurityContext,
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "must-gather-pvc",
+				MountPath: mustGatherDataPath,
+			},
+			{
+				Name:      "redaction-policy",
+				MountPath: redactionPolicyPath,
+				ReadOnly:  true,
+			},
+		},
+	}
+	volumes := []corev1.Volume{
+		{
+			Name: "redaction-policy",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: cr.Spec.MustGatherConfigName,
+					},
+					Items: []corev1.KeyToPath{{Key: redact.PolicyKey, Path: redact.PolicyKey}},
+				},
+			},
+		},
+	}
+
+	return newPostGatherJob(cr, gatherJob, claimName, name, "must-gather-redact", container, volumes)
+}
@@ -0,0 +1,89 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherjob
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitShellWords splits s into words as a POSIX shell does, without expansions: words are separated
+// by blanks, single quotes keep their content as is, double quotes keep it except for the \ escapes
+// of \, ", $, ` and newline, and a \ outside of quotes escapes the next character. A \ followed by
+// a newline is removed, a trailing \ is kept.
+func splitShellWords(s string) ([]string, error) {
+	words := []string{}
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			if i+1 == len(s) {
+				inWord = true
+				word.WriteByte(c)
+				break
+			}
+			i++
+			// a line continuation doesn't start a word
+			if s[i] != '\n' {
+				inWord = true
+				word.WriteByte(s[i])
+			}
+		case c == '\'':
+			inWord = true
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote in %q", s)
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+		case c == '"':
+			inWord = true
+			closed := false
+			for i++; i < len(s); i++ {
+				if s[i] == '"' {
+					closed = true
+					break
+				}
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\\\"$`\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(s[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated double quote in %q", s)
+			}
+		default:
+			inWord = true
+			word.WriteByte(c)
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
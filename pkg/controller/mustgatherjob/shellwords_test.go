@@ -0,0 +1,64 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherjob
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", s: "", want: []string{}},
+		{name: "blanks only", s: " \t\n ", want: []string{}},
+		{name: "single word", s: "gather", want: []string{"gather"}},
+		{name: "double spaces", s: "  gather  --since  1h  ", want: []string{"gather", "--since", "1h"}},
+		{name: "tabs and newlines", s: "gather\t--since\n1h", want: []string{"gather", "--since", "1h"}},
+		{name: "single quotes", s: `echo 'a  b' 'c"d' 'e\f'`, want: []string{"echo", "a  b", `c"d`, `e\f`}},
+		{name: "double quotes", s: `echo "a  b" "c'd" "$HOME"`, want: []string{"echo", "a  b", "c'd", "$HOME"}},
+		{name: "empty quotes", s: `a '' "" b`, want: []string{"a", "", "", "b"}},
+		{name: "quotes inside a word", s: `--label="app=a b"'c'd`, want: []string{"--label=app=a bcd"}},
+		{name: "escapes outside of quotes", s: `a\ b \'c\' \"d\" e\\f`, want: []string{"a b", "'c'", `"d"`, `e\f`}},
+		{name: "escapes in double quotes", s: `"a\"b" "c\\d" "\$e" "\` + "`" + `f" "g\h"`, want: []string{`a"b`, `c\d`, "$e", "`f", `g\h`}},
+		{name: "line continuation", s: "a \\\n b", want: []string{"a", "b"}},
+		{name: "line continuation inside a word", s: "a\\\nb", want: []string{"ab"}},
+		{name: "line continuation in double quotes", s: "\"a\\\nb\"", want: []string{"ab"}},
+		{name: "trailing backslash", s: `a\`, want: []string{`a\`}},
+		{name: "trailing backslash alone", s: `a \`, want: []string{"a", `\`}},
+		{name: "unterminated single quote", s: `echo 'a b`, wantErr: true},
+		{name: "unterminated double quote", s: `echo "a b`, wantErr: true},
+		{name: "escaped closing double quote", s: `echo "a\"`, wantErr: true},
+		{name: "single quote in double quotes is not unterminated", s: `"it's"`, want: []string{"it's"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitShellWords(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitShellWords(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitShellWords(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,470 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherjob
+
+import (
+	"fmt"
+	"path"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
+	"github.com/IBM/ibm-healthcheck-operator/pkg/integrity"
+	"github.com/IBM/ibm-healthcheck-operator/pkg/redact"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// mustGatherDataPath is where the must gather PVC is mounted in the job and in the must gather service
+const mustGatherDataPath = "/must-gather"
+
+// updateMustGatherJobStatus computes the phase and the conditions of the MustGatherJob from the batch Job
+// of the current run, if any, and writes the status once, as a patch from original
+func (r *ReconcileMustGatherJob) updateMustGatherJobStatus(original, instance *operatorv1alpha1.MustGatherJob, job *batchv1.Job) error {
+	reqLogger := log.WithValues("MustGatherJob.Namespace", instance.Namespace, "MustGatherJob.Name", instance.Name)
+
+	if job != nil {
+		pod, err := common.LatestJobPod(r.client, job)
+		if err != nil {
+			reqLogger.Error(err, "Failed to list pods of the must gather job")
+			return err
+		}
+		setJobStatus(&instance.Status, instance.Generation, job, pod)
+		instance.Status.ArtifactPath = path.Join(mustGatherDataPath, job.Name)
+	}
+
+	status := instance.Status.DeepCopy()
+	err := common.PatchStatus(r.client, r.reader, original, instance, func() {
+		original.DeepCopyInto(instance)
+		status.DeepCopyInto(&instance.Status)
+	})
+	if err != nil {
+		reqLogger.Error(err, "Failed to update MustGatherJob status")
+		return err
+	}
+	return nil
+}
+
+// setJobStatus sets the phase, the times, the pod and the conditions from the job and its latest pod
+func setJobStatus(status *operatorv1alpha1.MustGatherJobStatus, generation int64, job *batchv1.Job, pod *corev1.Pod) {
+	complete := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionComplete,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+	}
+	failed := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionFailed,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "AsExpected",
+		Message:            "The must gather job has not failed",
+	}
+
+	status.JobName = job.Name
+	status.Image = ""
+	if containers := job.Spec.Template.Spec.Containers; len(containers) > 0 {
+		status.Image = containers[0].Image
+	}
+	status.StartTime = job.Status.StartTime
+	status.CompletionTime = nil
+	status.FailureReason = ""
+	status.PodName = ""
+	if pod != nil {
+		status.PodName = pod.Name
+	}
+
+	succeededCondition := common.JobCondition(job, batchv1.JobComplete)
+	failedCondition := common.JobCondition(job, batchv1.JobFailed)
+	switch {
+	case succeededCondition != nil:
+		status.Phase = operatorv1alpha1.JobSucceeded
+		status.CompletionTime = job.Status.CompletionTime
+		if status.CompletionTime == nil {
+			status.CompletionTime = &succeededCondition.LastTransitionTime
+		}
+		complete.Status = metav1.ConditionTrue
+		complete.Reason = "JobSucceeded"
+		complete.Message = fmt.Sprintf("job %q completed", job.Name)
+	case failedCondition != nil:
+		status.Phase = operatorv1alpha1.JobFailed
+		status.CompletionTime = &failedCondition.LastTransitionTime
+		status.FailureReason = failedCondition.Reason
+		if status.FailureReason == "" {
+			status.FailureReason = "JobFailed"
+		}
+		complete.Reason = "JobFailed"
+		complete.Message = fmt.Sprintf("job %q failed", job.Name)
+		failed.Status = metav1.ConditionTrue
+		failed.Reason = status.FailureReason
+		failed.Message = fmt.Sprintf("job %q failed: %s", job.Name, failedCondition.Message)
+		if reason := podFailureReason(pod); reason != "" {
+			failed.Message = fmt.Sprintf("%s, last pod %q: %s", failed.Message, pod.Name, reason)
+		}
+	case pod != nil && pod.Status.Phase == corev1.PodRunning:
+		status.Phase = operatorv1alpha1.JobRunning
+		complete.Reason = "JobRunning"
+		complete.Message = fmt.Sprintf("pod %q is running", pod.Name)
+	default:
+		status.Phase = operatorv1alpha1.JobPending
+		complete.Reason = "JobPending"
+		complete.Message = fmt.Sprintf("job %q has no running pod yet", job.Name)
+	}
+
+	meta.SetStatusCondition(&status.Conditions, complete)
+	meta.SetStatusCondition(&status.Conditions, failed)
+}
+
+// setCancelledStatus sets the phase and the conditions of a cancelled gather
+func setCancelledStatus(status *operatorv1alpha1.MustGatherJobStatus, generation int64) {
+	now := metav1.Now()
+	status.Phase = operatorv1alpha1.JobCancelled
+	status.CompletionTime = &now
+	status.FailureReason = ""
+
+	message := "The must gather job was cancelled before it started"
+	if status.JobName != "" {
+		message = fmt.Sprintf("job %q was cancelled", status.JobName)
+	}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               operatorv1alpha1.ConditionComplete,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "JobCancelled",
+		Message:            message,
+	})
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               operatorv1alpha1.ConditionFailed,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "JobCancelled",
+		Message:            message,
+	})
+}
+
+// setInvalidSpecStatus sets the phase and the conditions of a MustGatherJob whose job can't be created from the spec
+func setInvalidSpecStatus(status *operatorv1alpha1.MustGatherJobStatus, generation int64, err error) {
+	status.Phase = operatorv1alpha1.JobFailed
+	status.FailureReason = "InvalidSpec"
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               operatorv1alpha1.ConditionComplete,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "InvalidSpec",
+		Message:            "The must gather job can't be created",
+	})
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               operatorv1alpha1.ConditionFailed,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "InvalidSpec",
+		Message:            err.Error(),
+	})
+}
+
+// podFailureReason returns the reason the gather container of the pod terminated with
+func podFailureReason(pod *corev1.Pod) string {
+	if pod == nil {
+		return ""
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if t := cs.State.Terminated; t != nil && t.ExitCode != 0 {
+			return fmt.Sprintf("%s, exit code %d", t.Reason, t.ExitCode)
+		}
+	}
+	return pod.Status.Reason
+}
+
+// setUploadStatus sets the upload state and the Uploaded condition from the gather job, and the upload
+// job and its latest pod once the gather succeeded
+func setUploadStatus(status *operatorv1alpha1.MustGatherJobStatus, generation int64, upload *operatorv1alpha1.Upload,
+	gatherJob, job *batchv1.Job, pod *corev1.Pod) {
+	if upload == nil {
+		status.Upload = nil
+		meta.RemoveStatusCondition(&status.Conditions, operatorv1alpha1.ConditionUploaded)
+		return
+	}
+
+	uploaded := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionUploaded,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+	}
+	defer func() {
+		meta.SetStatusCondition(&status.Conditions, uploaded)
+	}()
+
+	if job == nil {
+		if common.JobCondition(gatherJob, batchv1.JobFailed) != nil {
+			status.Upload = nil
+			uploaded.Reason = "GatherFailed"
+			uploaded.Message = fmt.Sprintf("job %q failed, there is no data to upload", gatherJob.Name)
+			return
+		}
+		if status.Redaction != nil && status.Redaction.Phase == operatorv1alpha1.JobFailed {
+			status.Upload = nil
+			uploaded.Reason = "RedactionFailed"
+			uploaded.Message = "The redaction failed, the data is not uploaded"
+			return
+		}
+		if redactionIncomplete(status) {
+			status.Upload = nil
+			uploaded.Reason = "RedactionIncomplete"
+			uploaded.Message = "The redaction skipped binary files, the data is not uploaded"
+			return
+		}
+		if status.Integrity != nil && status.Integrity.Phase == operatorv1alpha1.JobFailed {
+			status.Upload = nil
+			uploaded.Reason = "SigningFailed"
+			uploaded.Message = "The signing of the manifest failed, the data is not uploaded"
+			return
+		}
+		status.Upload = &operatorv1alpha1.UploadStatus{Phase: operatorv1alpha1.JobPending}
+		uploaded.Reason = "GatherNotComplete"
+		uploaded.Message = fmt.Sprintf("the data is uploaded to %s once job %q completes", upload.Endpoint, gatherJob.Name)
+		if common.JobCondition(gatherJob, batchv1.JobComplete) == nil {
+			return
+		}
+		if !redactionReady(status) {
+			uploaded.Reason = "RedactionNotComplete"
+			uploaded.Message = fmt.Sprintf("the data is uploaded to %s once it is redacted", upload.Endpoint)
+		} else {
+			uploaded.Reason = "SigningNotComplete"
+			uploaded.Message = fmt.Sprintf("the data is uploaded to %s once its manifest is signed", upload.Endpoint)
+		}
+		return
+	}
+
+	status.Upload = &operatorv1alpha1.UploadStatus{JobName: job.Name}
+	result := &uploadResult{}
+	if c := common.JobCondition(job, batchv1.JobFailed); c != nil {
+		status.Upload.Phase = operatorv1alpha1.JobFailed
+		status.Upload.CompletionTime = &c.LastTransitionTime
+		uploaded.Reason = "UploadFailed"
+		uploaded.Message = fmt.Sprintf("job %q failed: %s", job.Name, c.Message)
+		if reason := podFailureReason(pod); reason != "" {
+			uploaded.Message = fmt.Sprintf("%s, last pod %q: %s", uploaded.Message, pod.Name, reason)
+		}
+	} else if c := common.JobCondition(job, batchv1.JobComplete); c != nil {
+		status.Upload.CompletionTime = job.Status.CompletionTime
+		if status.Upload.CompletionTime == nil {
+			status.Upload.CompletionTime = &c.LastTransitionTime
+		}
+		if err := common.UnmarshalTerminationMessage(pod, result); err != nil {
+			status.Upload.Phase = operatorv1alpha1.JobFailed
+			uploaded.Reason = "InvalidUploadResult"
+			uploaded.Message = fmt.Sprintf("job %q: %v", job.Name, err)
+			return
+		}
+		status.Upload.URL = result.URL
+		status.Upload.Checksum = result.Checksum
+		status.Upload.ManifestURL = result.ManifestURL
+		status.Upload.SignatureURL = result.SignatureURL
+		if status.Integrity != nil && status.Integrity.Digest != result.Checksum {
+			status.Upload.Phase = operatorv1alpha1.JobFailed
+			uploaded.Reason = "ChecksumMismatch"
+			uploaded.Message = fmt.Sprintf("the checksum %s of %s is not the digest %s of the signed archive",
+				result.Checksum, result.URL, status.Integrity.Digest)
+			return
+		}
+		status.Upload.Phase = operatorv1alpha1.JobSucceeded
+		uploaded.Status = metav1.ConditionTrue
+		uploaded.Reason = "Uploaded"
+		uploaded.Message = fmt.Sprintf("the data is uploaded to %s", result.URL)
+	} else if pod != nil && pod.Status.Phase == corev1.PodRunning {
+		status.Upload.Phase = operatorv1alpha1.JobRunning
+		uploaded.Reason = "UploadRunning"
+		uploaded.Message = fmt.Sprintf("pod %q is uploading the data", pod.Name)
+	} else {
+		status.Upload.Phase = operatorv1alpha1.JobPending
+		uploaded.Reason = "UploadPending"
+		uploaded.Message = fmt.Sprintf("job %q has no running pod yet", job.Name)
+	}
+}
+
+// setRedactionStatus sets the redaction state, its counts and the Redacted condition from the gather job,
+// and the redaction job and its latest pod once the gather succeeded
+func setRedactionStatus(status *operatorv1alpha1.MustGatherJobStatus, generation int64, policy bool,
+	gatherJob, job *batchv1.Job, pod *corev1.Pod) {
+	if !policy {
+		status.Redaction = nil
+		meta.RemoveStatusCondition(&status.Conditions, operatorv1alpha1.ConditionRedacted)
+		return
+	}
+
+	redacted := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionRedacted,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+	}
+	defer func() {
+		meta.SetStatusCondition(&status.Conditions, redacted)
+	}()
+
+	if job == nil {
+		if common.JobCondition(gatherJob, batchv1.JobFailed) != nil {
+			status.Redaction = nil
+			redacted.Reason = "GatherFailed"
+			redacted.Message = fmt.Sprintf("job %q failed, there is no data to redact", gatherJob.Name)
+			return
+		}
+		status.Redaction = &operatorv1alpha1.RedactionStatus{Phase: operatorv1alpha1.JobPending}
+		redacted.Reason = "GatherNotComplete"
+		redacted.Message = fmt.Sprintf("the data is redacted once job %q completes", gatherJob.Name)
+		return
+	}
+
+	status.Redaction = &operatorv1alpha1.RedactionStatus{JobName: job.Name}
+	result := &redact.Result{}
+	if c := common.JobCondition(job, batchv1.JobFailed); c != nil {
+		status.Redaction.Phase = operatorv1alpha1.JobFailed
+		status.Redaction.CompletionTime = &c.LastTransitionTime
+		redacted.Reason = "RedactionFailed"
+		redacted.Message = fmt.Sprintf("job %q failed: %s", job.Name, c.Message)
+		if reason := podFailureReason(pod); reason != "" {
+			redacted.Message = fmt.Sprintf("%s, last pod %q: %s", redacted.Message, pod.Name, reason)
+		}
+	} else if c := common.JobCondition(job, batchv1.JobComplete); c != nil {
+		status.Redaction.CompletionTime = job.Status.CompletionTime
+		if status.Redaction.CompletionTime == nil {
+			status.Redaction.CompletionTime = &c.LastTransitionTime
+		}
+		if err := common.UnmarshalTerminationMessage(pod, result); err != nil {
+			status.Redaction.Phase = operatorv1alpha1.JobFailed
+			redacted.Reason = "InvalidRedactionResult"
+			redacted.Message = fmt.Sprintf("job %q: %v", job.Name, err)
+			return
+		}
+		status.Redaction.Phase = operatorv1alpha1.JobSucceeded
+		status.Redaction.FilesScanned = result.FilesScanned
+		status.Redaction.FilesRedacted = result.FilesRedacted
+		status.Redaction.RuleMatches = result.RuleMatches
+		status.Redaction.KeysRedacted = result.KeysRedacted
+		status.Redaction.ResourcesRemoved = result.ResourcesRemoved
+		status.Redaction.FilesSkipped = result.FilesSkipped
+		if result.FilesSkipped > 0 {
+			redacted.Reason = "FilesSkipped"
+			redacted.Message = fmt.Sprintf("%d of %d files redacted, %d binary or unparsable files can't be redacted",
+				result.FilesRedacted, result.FilesScanned, result.FilesSkipped)
+			return
+		}
+		redacted.Status = metav1.ConditionTrue
+		redacted.Reason = "Redacted"
+		redacted.Message = fmt.Sprintf("%d of %d files redacted", result.FilesRedacted, result.FilesScanned)
+	} else if pod != nil && pod.Status.Phase == corev1.PodRunning {
+		status.Redaction.Phase = operatorv1alpha1.JobRunning
+		redacted.Reason = "RedactionRunning"
+		redacted.Message = fmt.Sprintf("pod %q is redacting the data", pod.Name)
+	} else {
+		status.Redaction.Phase = operatorv1alpha1.JobPending
+		redacted.Reason = "RedactionPending"
+		redacted.Message = fmt.Sprintf("job %q has no running pod yet", job.Name)
+	}
+}
+
+// setIntegrityStatus sets the state of the signed manifest, the archive digest and the Signed condition from
+// the gather job, and the signing job and its latest pod once the gathered data is redacted
+func setIntegrityStatus(status *operatorv1alpha1.MustGatherJobStatus, generation int64, spec *operatorv1alpha1.Integrity,
+	gatherJob, job *batchv1.Job, pod *corev1.Pod) {
+	if spec == nil {
+		status.Integrity = nil
+		meta.RemoveStatusCondition(&status.Conditions, operatorv1alpha1.ConditionSigned)
+		return
+	}
+
+	signed := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionSigned,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+	}
+	defer func() {
+		meta.SetStatusCondition(&status.Conditions, signed)
+	}()
+
+	if job == nil {
+		if common.JobCondition(gatherJob, batchv1.JobFailed) != nil {
+			status.Integrity = nil
+			signed.Reason = "GatherFailed"
+			signed.Message = fmt.Sprintf("job %q failed, there is no data to sign", gatherJob.Name)
+			return
+		}
+		if status.Redaction != nil && status.Redaction.Phase == operatorv1alpha1.JobFailed {
+			status.Integrity = nil
+			signed.Reason = "RedactionFailed"
+			signed.Message = "The redaction failed, the data is not signed"
+			return
+		}
+		if redactionIncomplete(status) {
+			status.Integrity = nil
+			signed.Reason = "RedactionIncomplete"
+			signed.Message = "The redaction skipped binary files, the data is not signed"
+			return
+		}
+		status.Integrity = &operatorv1alpha1.IntegrityStatus{Phase: operatorv1alpha1.JobPending}
+		signed.Reason = "GatherNotComplete"
+		signed.Message = fmt.Sprintf("the manifest of the data is signed once job %q completes", gatherJob.Name)
+		if common.JobCondition(gatherJob, batchv1.JobComplete) != nil {
+			signed.Reason = "RedactionNotComplete"
+			signed.Message = "the manifest of the data is signed once the data is redacted"
+		}
+		return
+	}
+
+	status.Integrity = &operatorv1alpha1.IntegrityStatus{JobName: job.Name}
+	result := &integrity.Result{}
+	if c := common.JobCondition(job, batchv1.JobFailed); c != nil {
+		status.Integrity.Phase = operatorv1alpha1.JobFailed
+		status.Integrity.CompletionTime = &c.LastTransitionTime
+		signed.Reason = "SigningFailed"
+		signed.Message = fmt.Sprintf("job %q failed: %s", job.Name, c.Message)
+		if reason := podFailureReason(pod); reason != "" {
+			signed.Message = fmt.Sprintf("%s, last pod %q: %s", signed.Message, pod.Name, reason)
+		}
+	} else if c := common.JobCondition(job, batchv1.JobComplete); c != nil {
+		status.Integrity.CompletionTime = job.Status.CompletionTime
+		if status.Integrity.CompletionTime == nil {
+			status.Integrity.CompletionTime = &c.LastTransitionTime
+		}
+		if err := common.UnmarshalTerminationMessage(pod, result); err != nil {
+			status.Integrity.Phase = operatorv1alpha1.JobFailed
+			signed.Reason = "InvalidSigningResult"
+			signed.Message = fmt.Sprintf("job %q: %v", job.Name, err)
+			return
+		}
+		status.Integrity.Phase = operatorv1alpha1.JobSucceeded
+		status.Integrity.Archive = result.Archive
+		status.Integrity.Manifest = result.Manifest
+		status.Integrity.Signature = result.Signature
+		status.Integrity.Digest = result.Digest
+		status.Integrity.Files = result.Files
+		status.Integrity.Signed = true
+		status.Integrity.KeyFingerprint = result.KeyFingerprint
+		signed.Status = metav1.ConditionTrue
+		signed.Reason = "Signed"
+		signed.Message = fmt.Sprintf("the manifest of %d files of %s is signed with key %s", result.Files, result.Archive,
+			result.KeyFingerprint)
+	} else if pod != nil && pod.Status.Phase == corev1.PodRunning {
+		status.Integrity.Phase = operatorv1alpha1.JobRunning
+		signed.Reason = "SigningRunning"
+		signed.Message = fmt.Sprintf("pod %q is signing the manifest of the data", pod.Name)
+	} else {
+		status.Integrity.Phase = operatorv1alpha1.JobPending
+		signed.Reason = "SigningPending"
+		signed.Message = fmt.Sprintf("job %q has no running pod yet", job.Name)
+	}
+}
@@ -0,0 +1,276 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherjob
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"strings"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	"github.com/IBM/ibm-healthcheck-operator/pkg/artifact"
+	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// defaultUploadRegion is the region requests are signed for when the upload doesn't set it,
+	// MinIO accepts it by default
+	defaultUploadRegion = "us-east-1"
+	// uploadCAPath is where the CA of the object storage endpoint is mounted in the upload pod
+	uploadCAPath = "/etc/must-gather-upload"
+)
+
+// uploadScript puts the gathered data to the object storage, with signature version 4 requests in path
+// style. The signed archive is put as is, with its manifest and signature as sibling objects, else the
+// data is compressed first. The object URLs and the archive checksum are written in the termination message.
+const uploadScript = `set -eu
+cd /must-gather
+
+hmac() { printf '%s' "$2" | openssl dgst -sha256 -mac HMAC -macopt "hexkey:$1" | sed 's/^.* //'; }
+hex() { printf '%s' "$1" | od -An -v -tx1 | tr -d ' \n'; }
+
+endpoint=${ENDPOINT%/}
+host=${endpoint#*://}
+
+# put FILE NAME puts the file to the object <prefix>/<name> of the bucket, and sets its url and checksum
+put() {
+	file=$1
+	object="/$BUCKET/${PREFIX:+${PREFIX%/}/}$2"
+	url="$endpoint$object"
+	checksum=$(sha256sum "$file" | cut -d ' ' -f 1)
+	now=$(date -u +%Y%m%dT%H%M%SZ)
+	day=${now%%T*}
+	scope="$day/$REGION/s3/aws4_request"
+	headers="host;x-amz-content-sha256;x-amz-date"
+
+	request="PUT
+$object
+
+host:$host
+x-amz-content-sha256:$checksum
+x-amz-date:$now
+
+$headers
+$checksum"
+	sts="AWS4-HMAC-SHA256
+$now
+$scope
+$(printf '%s' "$request" | sha256sum | cut -d ' ' -f 1)"
+
+	k=$(hmac "$(hex "AWS4$AWS_SECRET_ACCESS_KEY")" "$day")
+	k=$(hmac "$k" "$REGION")
+	k=$(hmac "$k" s3)
+	k=$(hmac "$k" aws4_request)
+	signature=$(hmac "$k" "$sts")
+
+	set --
+	[ -n "${TLS_CA_FILE:-}" ] && set -- --cacert "$TLS_CA_FILE"
+	[ "${TLS_INSECURE:-false}" = "true" ] && set -- "$@" --insecure
+	echo "uploading $file to $url"
+	curl -sS --fail --retry 3 "$@" -X PUT -T "$file" \
+		-H "Host: $host" \
+		-H "x-amz-date: $now" \
+		-H "x-amz-content-sha256: $checksum" \
+		-H "Authorization: AWS4-HMAC-SHA256 Credential=$AWS_ACCESS_KEY_ID/$scope, SignedHeaders=$headers, Signature=$signature" \
+		"$url"
+}
+
+if [ -n "${SIGNED_ARCHIVE:-}" ]; then
+	put "$SIGNED_MANIFEST" "$SIGNED_MANIFEST"
+	manifest=$url
+	put "$SIGNED_SIGNATURE" "$SIGNED_SIGNATURE"
+	sig=$url
+	put "$SIGNED_ARCHIVE" "$SIGNED_ARCHIVE"
+	printf '{"url":"%s","checksum":"sha256:%s","manifestURL":"%s","signatureURL":"%s"}' \
+		"$url" "$checksum" "$manifest" "$sig" > /dev/termination-log
+	exit 0
+fi
+
+set --
+for a in $ARTIFACT_NAMES; do
+	if [ -e "$a" ]; then set -- "$@" "$a"; fi
+done
+[ $# -gt 0 ] || { echo "no gathered data $ARTIFACT" >&2; exit 1; }
+archive="/tmp/$ARTIFACT.tar.gz"
+tar -czf "$archive" "$@"
+put "$archive" "$ARTIFACT.tar.gz"
+rm -f "$archive"
+printf '{"url":"%s","checksum":"sha256:%s"}' "$url" "$checksum" > /dev/termination-log
+`
+
+// uploadResult is the termination message of the upload pod
+type uploadResult struct {
+	URL          string `json:"url"`
+	Checksum     string `json:"checksum"`
+	ManifestURL  string `json:"manifestURL"`
+	SignatureURL string `json:"signatureURL"`
+}
+
+// reconcileUpload creates the upload job once the gather job succeeded and its data is redacted and signed,
+// and records the state of the upload
+func (r *ReconcileMustGatherJob) reconcileUpload(cr *operatorv1alpha1.MustGatherJob, gatherJob *batchv1.Job) error {
+	reqLogger := log.WithValues("MustGatherJob.Namespace", cr.Namespace, "MustGatherJob.Name", cr.Name)
+
+	upload, err := r.mustGatherUpload(cr)
+	if err != nil {
+		return err
+	}
+	if upload == nil || common.JobCondition(gatherJob, batchv1.JobComplete) == nil ||
+		!redactionReady(&cr.Status) || !integrityReady(&cr.Status) {
+		setUploadStatus(&cr.Status, cr.Generation, upload, gatherJob, nil, nil)
+		return nil
+	}
+
+	job := &batchv1.Job{}
+	name := postGatherJobName(gatherJob.Name, "-upload")
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: cr.Namespace}, job)
+	if err != nil && errors.IsNotFound(err) {
+		job = newUploadJob(cr, gatherJob, gatherClaimName(gatherJob), upload, cr.Status.Integrity, name)
+		if err := controllerutil.SetControllerReference(cr, job, r.scheme); err != nil {
+			return err
+		}
+		reqLogger.Info("Creating a new upload job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		if err := r.client.Create(context.TODO(), job); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	pod, err := common.LatestJobPod(r.client, job)
+	if err != nil {
+		return err
+	}
+	setUploadStatus(&cr.Status, cr.Generation, upload, gatherJob, job, pod)
+	return nil
+}
+
+// mustGatherUpload returns the upload of the MustGatherJob, else the upload of its MustGatherService,
+// nil when the gathered data is not uploaded
+func (r *ReconcileMustGatherJob) mustGatherUpload(cr *operatorv1alpha1.MustGatherJob) (*operatorv1alpha1.Upload, error) {
+	if cr.Spec.Upload != nil {
+		return cr.Spec.Upload, nil
+	}
+	mgs, err := common.SelectMustGatherService(r.client, cr.Namespace, cr.Spec.MustGatherServiceName)
+	if err != nil || mgs == nil {
+		return nil, err
+	}
+	return mgs.Spec.Upload, nil
+}
+
+// newUploadJob returns the job which uploads the data of the gather job of the cr, the signed archive
+// when there is one. Its pod is scheduled as the gather pod, and runs the must gather image with the
+// must gather volume only.
+func newUploadJob(cr *operatorv1alpha1.MustGatherJob, gatherJob *batchv1.Job, claimName string,
+	upload *operatorv1alpha1.Upload, signed *operatorv1alpha1.IntegrityStatus, name string) *batchv1.Job {
+	gather := gatherJob.Spec.Template.Spec.Containers[0]
+
+	region := upload.Region
+	if region == "" {
+		region = defaultUploadRegion
+	}
+
+	container := corev1.Container{
+		Name:            "upload",
+		Image:           gather.Image,
+		ImagePullPolicy: gather.ImagePullPolicy,
+		Command:         []string{"/bin/sh", "-c", uploadScript},
+		Resources:       gather.Resources,
+		SecurityContext: gather.SecurityContext,
+		Env: []corev1.EnvVar{
+			{Name: "ARTIFACT", Value: gatherJob.Name},
+			{Name: "ARTIFACT_NAMES", Value: strings.Join(artifact.Names(gatherJob.Name), " ")},
+			{Name: "ENDPOINT", Value: upload.Endpoint},
+			{Name: "BUCKET", Value: upload.Bucket},
+			{Name: "PREFIX", Value: upload.Prefix},
+			{Name: "REGION", Value: region},
+			{Name: "TLS_INSECURE", Value: strconv.FormatBool(upload.TLS.InsecureSkipVerify)},
+			{
+				Name: "AWS_ACCESS_KEY_ID",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: upload.CredentialsSecretName},
+						Key:                  "accessKeyID",
+					},
+				},
+			},
+			{
+				Name: "AWS_SECRET_ACCESS_KEY",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: upload.CredentialsSecretName},
+						Key:                  "secretAccessKey",
+					},
+				},
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "must-gather-pvc",
+				MountPath: mustGatherDataPath,
+				ReadOnly:  true,
+			},
+			{
+				Name:      "tmp",
+				MountPath: "/tmp",
+			},
+		},
+	}
+	volumes := []corev1.Volume{
+		{
+			Name: "tmp",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+	}
+
+	if signed != nil && signed.Phase == operatorv1alpha1.JobSucceeded {
+		container.Env = append(container.Env,
+			corev1.EnvVar{Name: "SIGNED_ARCHIVE", Value: signed.Archive},
+			corev1.EnvVar{Name: "SIGNED_MANIFEST", Value: signed.Manifest},
+			corev1.EnvVar{Name: "SIGNED_SIGNATURE", Value: signed.Signature},
+		)
+	}
+
+	if upload.TLS.CASecretName != "" {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "TLS_CA_FILE", Value: path.Join(uploadCAPath, "ca.crt")})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "upload-ca",
+			MountPath: uploadCAPath,
+			ReadOnly:  true,
+		})
+		volumes = append(volumes, corev1.Volume{
+			Name: "upload-ca",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: upload.TLS.CASecretName,
+					Items:      []corev1.KeyToPath{{Key: "ca.crt", Path: "ca.crt"}},
+				},
+			},
+		})
+	}
+
+	return newPostGatherJob(cr, gatherJob, claimName, name, "must-gather-upload", container, volumes)
+}
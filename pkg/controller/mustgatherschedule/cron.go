@@ -0,0 +1,225 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherschedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// the operator image has no zoneinfo, the time zones of the schedules are embedded
+	_ "time/tzdata"
+)
+
+// cronSchedule is a parsed cron expression in the standard five fields format:
+// minute, hour, day of month, month and day of week
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar or dowStar is set when the day of month or the day of week field is *,
+	// the day matches the other field only. Otherwise it matches either field.
+	domStar, dowStar bool
+	location         *time.Location
+}
+
+// cronField is the range of the values of a field
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is also Sunday
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// cronMacros are the supported shorthands of the five fields
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCronSchedule parses the cron expression spec, whose times are in the location loc
+func parseCronSchedule(spec string, loc *time.Location) (*cronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in cron expression %q, found %d", spec, len(fields))
+	}
+
+	s := &cronSchedule{location: loc}
+	var err error
+	if s.minute, err = parseCronField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseCronField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseCronField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseCronField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseCronField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+	// Sunday is 0 in time.Weekday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domStar = strings.HasPrefix(fields[2], "*")
+	s.dowStar = strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// parseCronField returns the bit set of the values of a comma separated list of
+// values, ranges a-b, and steps */n, a-b/n or a/n
+func parseCronField(value string, field cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(value, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", field.name, item)
+			}
+			rangePart, step = item[:i], n
+		}
+
+		var low, high int
+		switch {
+		case rangePart == "*":
+			low, high = field.min, field.max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = field.parseValue(bounds[0]); err != nil {
+				return 0, err
+			}
+			if high, err = field.parseValue(bounds[1]); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range in %s field %q", field.name, item)
+			}
+		default:
+			var err error
+			if low, err = field.parseValue(rangePart); err != nil {
+				return 0, err
+			}
+			high = low
+			if step > 1 {
+				high = field.max
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// parseValue parses a number or a name of the field
+func (f cronField) parseValue(value string) (int, error) {
+	if v, ok := f.names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", value, f.name)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d of %s field is out of range %d-%d", v, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// next returns the first time of the schedule after t, the zero time when there is none in the next 5 years
+func (s *cronSchedule) next(t time.Time) time.Time {
+	loc := s.location
+	t = t.In(loc).Truncate(time.Minute).Add(time.Minute)
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = forward(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+	for !s.dayMatches(t) {
+		t = forward(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		t = forward(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc))
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+	return t
+}
+
+// forward returns next, the start of the next month, day or hour after t, moved past the daylight saving
+// time gap it may fall in. time.Date normalizes a time in the gap to a time before the gap.
+func forward(t, next time.Time) time.Time {
+	for !next.After(t) {
+		next = next.Add(time.Hour)
+	}
+	return next
+}
+
+// dayMatches returns true when the day of t matches the day of month and the day of week fields
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,249 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherschedule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name     string
+		spec     string
+		location *time.Location
+		from     time.Time
+		want     time.Time
+	}{
+		{
+			name: "hourly macro",
+			spec: "@hourly",
+			from: time.Date(2021, 6, 1, 10, 30, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 1, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "daily macro",
+			spec: "@daily",
+			from: time.Date(2021, 6, 1, 10, 30, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "midnight macro",
+			spec: "@midnight",
+			from: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "weekly macro runs on sunday",
+			spec: "@weekly",
+			from: time.Date(2021, 6, 1, 10, 30, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "monthly macro",
+			spec: "@monthly",
+			from: time.Date(2021, 6, 1, 10, 30, 0, 0, time.UTC),
+			want: time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "yearly macro",
+			spec: "@yearly",
+			from: time.Date(2021, 6, 1, 10, 30, 0, 0, time.UTC),
+			want: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "annually macro in upper case",
+			spec: "@ANNUALLY",
+			from: time.Date(2021, 12, 31, 23, 59, 0, 0, time.UTC),
+			want: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "next minute of a time in the middle of a minute",
+			spec: "* * * * *",
+			from: time.Date(2021, 6, 1, 10, 30, 45, 0, time.UTC),
+			want: time.Date(2021, 6, 1, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			name: "step of a star",
+			spec: "*/15 * * * *",
+			from: time.Date(2021, 6, 1, 10, 7, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 1, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name: "step of a range",
+			spec: "0 9-17/4 * * *",
+			from: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "step of a range past its end",
+			spec: "0 9-17/4 * * *",
+			from: time.Date(2021, 6, 1, 17, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "step from a value",
+			spec: "5/20 * * * *",
+			from: time.Date(2021, 6, 1, 10, 6, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 1, 10, 25, 0, 0, time.UTC),
+		},
+		{
+			name: "list of values",
+			spec: "0,30 * * * *",
+			from: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 1, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "month names",
+			spec: "0 0 1 mar,SEP *",
+			from: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day of month or day of week, day of week first",
+			spec: "0 0 13 * fri",
+			from: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day of month or day of week, day of month first",
+			spec: "0 0 13 * fri",
+			from: time.Date(2021, 6, 12, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 13, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day of week only when the day of month is a star",
+			spec: "0 0 * * fri",
+			from: time.Date(2021, 6, 12, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 18, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day of month only when the day of week is a star",
+			spec: "0 0 13 * *",
+			from: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 13, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "step of a star day of month and day of week",
+			spec: "0 0 */2 * mon",
+			from: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 7, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "sunday as 7",
+			spec: "0 0 * * 7",
+			from: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "range ending with sunday as 7",
+			spec: "0 0 * * 6-7",
+			from: time.Date(2021, 6, 5, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2021, 6, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "february 29",
+			spec: "0 0 29 2 *",
+			from: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "no time in the next 5 years",
+			spec: "0 0 31 2 *",
+			from: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "time zone",
+			spec:     "0 9 * * *",
+			location: newYork,
+			from:     time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+			want:     time.Date(2021, 6, 1, 9, 0, 0, 0, newYork),
+		},
+		{
+			name:     "time in the daylight saving time gap is skipped",
+			spec:     "30 2 * * *",
+			location: newYork,
+			from:     time.Date(2021, 3, 13, 3, 0, 0, 0, newYork),
+			want:     time.Date(2021, 3, 15, 2, 30, 0, 0, newYork),
+		},
+		{
+			name:     "hour after the daylight saving time gap",
+			spec:     "0 3 * * *",
+			location: newYork,
+			from:     time.Date(2021, 3, 14, 0, 0, 0, 0, newYork),
+			want:     time.Date(2021, 3, 14, 3, 0, 0, 0, newYork),
+		},
+		{
+			name:     "every hour across the daylight saving time gap",
+			spec:     "0 * * * *",
+			location: newYork,
+			from:     time.Date(2021, 3, 14, 1, 0, 0, 0, newYork),
+			want:     time.Date(2021, 3, 14, 3, 0, 0, 0, newYork),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := tt.location
+			if loc == nil {
+				loc = time.UTC
+			}
+			s, err := parseCronSchedule(tt.spec, loc)
+			if err != nil {
+				t.Fatalf("parseCronSchedule(%q) error = %v", tt.spec, err)
+			}
+			if got := s.next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"@reboot",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"1,,2 * * * *",
+	} {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := parseCronSchedule(spec, time.UTC); err == nil {
+				t.Errorf("parseCronSchedule(%q) error = nil, want an error", spec)
+			}
+		})
+	}
+}
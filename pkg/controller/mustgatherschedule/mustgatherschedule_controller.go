@@ -0,0 +1,370 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherschedule
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"sort"
+	"time"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_mustgatherschedule")
+
+const (
+	// scheduledTimeAnnotation is the scheduled time of the run of a MustGatherJob
+	scheduledTimeAnnotation = "healthcheck.ibm.com/scheduled-time"
+	// maxRunNamePrefix keeps the names of the MustGatherJobs, and of their batch Jobs, short enough for a label value
+	maxRunNamePrefix                  = 40
+	defaultSuccessfulJobsHistoryLimit = 3
+	defaultFailedJobsHistoryLimit     = 1
+)
+
+// Add creates a new MustGatherSchedule Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileMustGatherSchedule{client: mgr.GetClient(), reader: mgr.GetAPIReader(), scheme: mgr.GetScheme()}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("mustgatherschedule-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource MustGatherSchedule
+	err = c.Watch(&source.Kind{Type: &operatorv1alpha1.MustGatherSchedule{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the MustGatherJobs of the runs and requeue the owner MustGatherSchedule
+	err = c.Watch(&source.Kind{Type: &operatorv1alpha1.MustGatherJob{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &operatorv1alpha1.MustGatherSchedule{},
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// blank assignment to verify that ReconcileMustGatherSchedule implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileMustGatherSchedule{}
+
+// ReconcileMustGatherSchedule reconciles a MustGatherSchedule object
+type ReconcileMustGatherSchedule struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	// reader reads from the apiserver, the status is patched from the latest object read with it
+	reader client.Reader
+	scheme *runtime.Scheme
+}
+
+// Reconcile creates a MustGatherJob from the template of the MustGatherSchedule at each scheduled time,
+// the batch Job of the run is then created by the MustGatherJob controller. It deletes the MustGatherJobs
+// beyond the history limits, and requeues the request at the next scheduled time.
+func (r *ReconcileMustGatherSchedule) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.Info("Reconciling MustGatherSchedule")
+
+	// Fetch the MustGatherSchedule instance
+	instance := &operatorv1alpha1.MustGatherSchedule{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	// The status is gathered in instance and written once, as a patch from original
+	original := instance.DeepCopy()
+
+	schedule, err := parseSchedule(&instance.Spec)
+	setScheduleValidCondition(&instance.Status.Conditions, instance.Generation, err)
+	if err != nil {
+		// the request is retried when the spec is fixed
+		reqLogger.Error(err, "Invalid schedule")
+		instance.Status.NextScheduleTime = nil
+		return reconcile.Result{}, r.updateMustGatherScheduleStatus(original, instance)
+	}
+
+	active, err := r.reconcileRuns(instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	now := time.Now()
+	if scheduled := missedRun(instance, schedule, now); !scheduled.IsZero() {
+		if err := r.startRun(instance, active, scheduled); err != nil {
+			reqLogger.Error(err, "Failed to start the scheduled run", "ScheduledTime", scheduled)
+			return reconcile.Result{}, err
+		}
+	}
+
+	result := reconcile.Result{}
+	instance.Status.NextScheduleTime = nil
+	if next := schedule.next(now); !next.IsZero() {
+		instance.Status.NextScheduleTime = &metav1.Time{Time: next}
+		result.RequeueAfter = next.Sub(now)
+	}
+	if err := r.updateMustGatherScheduleStatus(original, instance); err != nil {
+		return reconcile.Result{}, err
+	}
+	return result, nil
+}
+
+// parseSchedule parses the cron expression in the time zone of the spec
+func parseSchedule(spec *operatorv1alpha1.MustGatherScheduleSpec) (*cronSchedule, error) {
+	loc := time.UTC
+	if spec.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(spec.TimeZone); err != nil {
+			return nil, &scheduleError{"InvalidTimeZone", fmt.Errorf("invalid time zone %q: %v", spec.TimeZone, err)}
+		}
+	}
+	schedule, err := parseCronSchedule(spec.Schedule, loc)
+	if err != nil {
+		return nil, &scheduleError{"InvalidSchedule", err}
+	}
+	return schedule, nil
+}
+
+// reconcileRuns deletes the finished MustGatherJobs beyond the history limits, records the active ones
+// and the last success in the status, and returns the active ones
+func (r *ReconcileMustGatherSchedule) reconcileRuns(instance *operatorv1alpha1.MustGatherSchedule) ([]*operatorv1alpha1.MustGatherJob, error) {
+	jobList := &operatorv1alpha1.MustGatherJobList{}
+	if err := r.client.List(context.TODO(), jobList, client.InNamespace(instance.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var active, succeeded, failed []*operatorv1alpha1.MustGatherJob
+	for i := range jobList.Items {
+		job := &jobList.Items[i]
+		if !metav1.IsControlledBy(job, instance) {
+			continue
+		}
+		switch job.Status.Phase {
+		case operatorv1alpha1.JobSucceeded:
+			succeeded = append(succeeded, job)
+			if t := job.Status.CompletionTime; t != nil {
+				last := instance.Status.LastSuccessfulTime
+				if last == nil || last.Before(t) {
+					instance.Status.LastSuccessfulTime = t.DeepCopy()
+				}
+			}
+		case operatorv1alpha1.JobFailed, operatorv1alpha1.JobCancelled:
+			failed = append(failed, job)
+		default:
+			active = append(active, job)
+		}
+	}
+
+	if err := r.pruneRuns(succeeded, historyLimit(instance.Spec.SuccessfulJobsHistoryLimit, defaultSuccessfulJobsHistoryLimit)); err != nil {
+		return nil, err
+	}
+	if err := r.pruneRuns(failed, historyLimit(instance.Spec.FailedJobsHistoryLimit, defaultFailedJobsHistoryLimit)); err != nil {
+		return nil, err
+	}
+
+	instance.Status.Active = nil
+	for _, job := range active {
+		instance.Status.Active = append(instance.Status.Active, job.Name)
+	}
+	sort.Strings(instance.Status.Active)
+	return active, nil
+}
+
+// pruneRuns deletes the oldest MustGatherJobs beyond limit, their batch Jobs are garbage collected
+func (r *ReconcileMustGatherSchedule) pruneRuns(jobs []*operatorv1alpha1.MustGatherJob, limit int) error {
+	if len(jobs) <= limit {
+		return nil
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreationTimestamp.Before(&jobs[j].CreationTimestamp) })
+	for _, job := range jobs[:len(jobs)-limit] {
+		log.Info("Deleting MustGatherJob beyond the history limit", "MustGatherJob.Namespace", job.Namespace, "MustGatherJob.Name", job.Name)
+		err := r.client.Delete(context.TODO(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func historyLimit(limit *int32, defaultLimit int) int {
+	if limit == nil || *limit < 0 {
+		return defaultLimit
+	}
+	return int(*limit)
+}
+
+// missedRun returns the latest scheduled time after the last run and before now, the zero time when
+// there is none, or when it is past the starting deadline
+func missedRun(instance *operatorv1alpha1.MustGatherSchedule, schedule *cronSchedule, now time.Time) time.Time {
+	earliest := instance.CreationTimestamp.Time
+	if instance.Status.LastScheduleTime != nil {
+		earliest = instance.Status.LastScheduleTime.Time
+	}
+	if d := instance.Spec.StartingDeadlineSeconds; d != nil {
+		if deadline := now.Add(-time.Duration(*d) * time.Second); deadline.After(earliest) {
+			earliest = deadline
+		}
+	}
+
+	var scheduled time.Time
+	for t := schedule.next(earliest); !t.IsZero() && !t.After(now); t = schedule.next(t) {
+		scheduled = t
+	}
+	return scheduled
+}
+
+// startRun creates the MustGatherJob of the run scheduled at scheduled, according to the concurrency policy
+func (r *ReconcileMustGatherSchedule) startRun(instance *operatorv1alpha1.MustGatherSchedule, active []*operatorv1alpha1.MustGatherJob, scheduled time.Time) error {
+	reqLogger := log.WithValues("MustGatherSchedule.Namespace", instance.Namespace, "MustGatherSchedule.Name", instance.Name)
+
+	if instance.Spec.Suspend {
+		reqLogger.Info("Skip the scheduled run: the schedule is suspended", "ScheduledTime", scheduled)
+		return nil
+	}
+
+	switch instance.Spec.ConcurrencyPolicy {
+	case operatorv1alpha1.AllowConcurrent:
+	case operatorv1alpha1.ReplaceConcurrent:
+		for _, job := range active {
+			reqLogger.Info("Deleting the MustGatherJob replaced by the scheduled run", "MustGatherJob.Name", job.Name)
+			err := r.client.Delete(context.TODO(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		instance.Status.Active = nil
+	default:
+		// the run is started once the previous runs are finished, unless it is past the starting deadline
+		if len(active) > 0 {
+			reqLogger.Info("Skip the scheduled run: a previous run is not finished", "ScheduledTime", scheduled)
+			return nil
+		}
+	}
+
+	job := newScheduledMustGatherJob(instance, scheduled)
+	if err := controllerutil.SetControllerReference(instance, job, r.scheme); err != nil {
+		return err
+	}
+	reqLogger.Info("Creating a new MustGatherJob", "MustGatherJob.Name", job.Name, "ScheduledTime", scheduled)
+	if err := r.client.Create(context.TODO(), job); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	instance.Status.Active = append(instance.Status.Active, job.Name)
+	sort.Strings(instance.Status.Active)
+	instance.Status.LastScheduleTime = &metav1.Time{Time: scheduled}
+	return nil
+}
+
+// newScheduledMustGatherJob returns the MustGatherJob of the run scheduled at scheduled. Its name is unique
+// per scheduled time, so a run is created once.
+func newScheduledMustGatherJob(cr *operatorv1alpha1.MustGatherSchedule, scheduled time.Time) *operatorv1alpha1.MustGatherJob {
+	prefix := cr.Name
+	if len(prefix) > maxRunNamePrefix {
+		prefix = prefix[:maxRunNamePrefix]
+	}
+	return &operatorv1alpha1.MustGatherJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", prefix, scheduled.Unix()/60),
+			Namespace: cr.Namespace,
+			Annotations: map[string]string{
+				scheduledTimeAnnotation: scheduled.UTC().Format(time.RFC3339),
+			},
+		},
+		Spec: cr.Spec.JobTemplate,
+	}
+}
+
+// scheduleError is an invalid cron expression or time zone, reported with the ScheduleValid condition
+type scheduleError struct {
+	reason string
+	err    error
+}
+
+func (e *scheduleError) Error() string {
+	return e.err.Error()
+}
+
+// setScheduleValidCondition sets the ScheduleValid condition, false if err is a scheduleError
+func setScheduleValidCondition(conditions *[]metav1.Condition, generation int64, err error) {
+	scheduleValid := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionScheduleValid,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "ScheduleParsed",
+		Message:            "The cron expression and the time zone are valid",
+	}
+	var scheduleErr *scheduleError
+	if goerrors.As(err, &scheduleErr) {
+		scheduleValid.Status = metav1.ConditionFalse
+		scheduleValid.Reason = scheduleErr.reason
+		scheduleValid.Message = scheduleErr.Error()
+	}
+	meta.SetStatusCondition(conditions, scheduleValid)
+}
+
+// updateMustGatherScheduleStatus writes the status gathered during the reconcile once, as a patch from original
+func (r *ReconcileMustGatherSchedule) updateMustGatherScheduleStatus(original, instance *operatorv1alpha1.MustGatherSchedule) error {
+	reqLogger := log.WithValues("MustGatherSchedule.Namespace", instance.Namespace, "MustGatherSchedule.Name", instance.Name)
+
+	status := instance.Status.DeepCopy()
+	err := common.PatchStatus(r.client, r.reader, original, instance, func() {
+		original.DeepCopyInto(instance)
+		status.DeepCopyInto(&instance.Status)
+	})
+	if err != nil {
+		reqLogger.Error(err, "Failed to update MustGatherSchedule status")
+		return err
+	}
+	return nil
+}
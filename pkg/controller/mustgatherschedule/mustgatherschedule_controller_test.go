@@ -0,0 +1,221 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherschedule
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var created = time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+func newSchedule(policy operatorv1alpha1.ConcurrencyPolicy) *operatorv1alpha1.MustGatherSchedule {
+	return &operatorv1alpha1.MustGatherSchedule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "nightly",
+			Namespace:         "ibm-common-services",
+			UID:               types.UID("nightly-uid"),
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: operatorv1alpha1.MustGatherScheduleSpec{
+			Schedule:          "*/15 * * * *",
+			ConcurrencyPolicy: policy,
+		},
+	}
+}
+
+func TestMissedRun(t *testing.T) {
+	at := func(hour, minute int) time.Time { return time.Date(2021, 6, 1, hour, minute, 0, 0, time.UTC) }
+	seconds := func(s int64) *int64 { return &s }
+
+	tests := []struct {
+		name             string
+		lastScheduleTime time.Time
+		deadline         *int64
+		now              time.Time
+		want             time.Time
+	}{
+		{
+			name: "first run since the creation",
+			now:  at(10, 20),
+			want: at(10, 15),
+		},
+		{
+			name: "no run before the first scheduled time",
+			now:  at(10, 14),
+		},
+		{
+			name: "run at the scheduled time",
+			now:  at(10, 15),
+			want: at(10, 15),
+		},
+		{
+			name:             "no run after the last scheduled run",
+			lastScheduleTime: at(10, 15),
+			now:              at(10, 20),
+		},
+		{
+			name:             "latest of the missed runs",
+			lastScheduleTime: at(8, 0),
+			now:              at(10, 20),
+			want:             at(10, 15),
+		},
+		{
+			name:             "missed run past the starting deadline",
+			lastScheduleTime: at(10, 0),
+			deadline:         seconds(60),
+			now:              at(10, 20),
+		},
+		{
+			name:             "missed run within the starting deadline",
+			lastScheduleTime: at(10, 0),
+			deadline:         seconds(600),
+			now:              at(10, 20),
+			want:             at(10, 15),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newSchedule("")
+			if !tt.lastScheduleTime.IsZero() {
+				instance.Status.LastScheduleTime = &metav1.Time{Time: tt.lastScheduleTime}
+			}
+			instance.Spec.StartingDeadlineSeconds = tt.deadline
+			schedule, err := parseSchedule(&instance.Spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := missedRun(instance, schedule, tt.now); !got.Equal(tt.want) {
+				t.Errorf("missedRun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartRunConcurrencyPolicy(t *testing.T) {
+	scheduled := time.Date(2021, 6, 1, 10, 15, 0, 0, time.UTC)
+	run := newScheduledMustGatherJob(newSchedule(""), scheduled).Name
+
+	tests := []struct {
+		name    string
+		policy  operatorv1alpha1.ConcurrencyPolicy
+		suspend bool
+		active  bool
+		// want are the MustGatherJobs after the run is started
+		want []string
+	}{
+		{
+			name:   "forbid by default without an active run",
+			policy: "",
+			want:   []string{run},
+		},
+		{
+			name:   "forbid by default with an active run",
+			policy: "",
+			active: true,
+			want:   []string{"nightly-previous"},
+		},
+		{
+			name:   "forbid with an active run",
+			policy: operatorv1alpha1.ForbidConcurrent,
+			active: true,
+			want:   []string{"nightly-previous"},
+		},
+		{
+			name:   "allow with an active run",
+			policy: operatorv1alpha1.AllowConcurrent,
+			active: true,
+			want:   []string{run, "nightly-previous"},
+		},
+		{
+			name:   "replace an active run",
+			policy: operatorv1alpha1.ReplaceConcurrent,
+			active: true,
+			want:   []string{run},
+		},
+		{
+			name:    "suspended",
+			policy:  operatorv1alpha1.AllowConcurrent,
+			suspend: true,
+			want:    []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := runtime.NewScheme()
+			if err := operatorv1alpha1.SchemeBuilder.AddToScheme(s); err != nil {
+				t.Fatal(err)
+			}
+			instance := newSchedule(tt.policy)
+			instance.Spec.Suspend = tt.suspend
+			objs := []runtime.Object{instance}
+			var active []*operatorv1alpha1.MustGatherJob
+			if tt.active {
+				previous := &operatorv1alpha1.MustGatherJob{
+					ObjectMeta: metav1.ObjectMeta{Name: "nightly-previous", Namespace: instance.Namespace},
+					Status:     operatorv1alpha1.MustGatherJobStatus{Phase: operatorv1alpha1.JobRunning},
+				}
+				objs = append(objs, previous)
+				active = append(active, previous)
+			}
+			c := fake.NewFakeClientWithScheme(s, objs...)
+			r := &ReconcileMustGatherSchedule{client: c, reader: c, scheme: s}
+
+			if err := r.startRun(instance, active, scheduled); err != nil {
+				t.Fatalf("startRun() error = %v", err)
+			}
+
+			jobList := &operatorv1alpha1.MustGatherJobList{}
+			if err := r.client.List(context.TODO(), jobList, client.InNamespace(instance.Namespace)); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, job := range jobList.Items {
+				got = append(got, job.Name)
+			}
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("MustGatherJobs = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("MustGatherJobs = %v, want %v", got, tt.want)
+				}
+			}
+
+			started := false
+			for _, name := range tt.want {
+				started = started || name == run
+			}
+			if started != (instance.Status.LastScheduleTime != nil) {
+				t.Errorf("LastScheduleTime = %v, want it set: %v", instance.Status.LastScheduleTime, started)
+			}
+		})
+	}
+}
@@ -17,9 +17,11 @@ package mustgatherservice
 
 import (
 	"context"
+	goerrors "errors"
+	"fmt"
 	"io/ioutil"
 	"os"
-	"reflect"
+	"strings"
 
 	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
 
@@ -34,6 +36,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -45,8 +48,6 @@ var gracePeriod = int64(60)
 var trueVar = true
 var falseVar = false
 
-var mustGatherResourceName = "must-gather-service"
-
 var mustGatherCustomCMName = "ibm-mustgather-customscript-default"
 
 var commonSecurityContext = corev1.SecurityContext{
@@ -66,74 +67,69 @@ func (r *ReconcileMustGatherService) createOrUpdateMustGatherServiceStatefulSet(
 
 	// Define a new StatefulSet
 	desired := r.desiredMustGatherServiceStatefulset(instance)
-	// Check if the StatefulSet already exists, if not create a new one
-	current := &appsv1.StatefulSet{}
-	err := r.client.Get(context.TODO(), types.NamespacedName{Name: mustGatherResourceName, Namespace: instance.Namespace}, current)
-
-	if err != nil && errors.IsNotFound(err) {
-		reqLogger.Info("Creating a new StatefulSet", "StatefulSet.Namespace", desired.Namespace, "StatefulSet.Name", desired.Name)
-		if err := r.client.Create(context.TODO(), desired); err != nil {
-			reqLogger.Error(err, "Failed to create new StatefulSet", "StatefulSet.Namespace", desired.Namespace, "StatefulSet.Name", desired.Name)
-			return err
-		}
-	} else if err != nil {
-		reqLogger.Error(err, "Failed to get StatefulSet", "StatefulSet.Namespace", current.Namespace, "StatefulSet.Name", current.Name)
+	// Pull the images from their registry mirror
+	if err := common.SetMirroredImages(r.client, &desired.Spec.Template); err != nil {
+		reqLogger.Error(err, "Failed to get the registry mirrors", "StatefulSet.Namespace", desired.Namespace, "StatefulSet.Name", desired.Name)
+		return err
+	}
+	// Roll the pods out when a mounted configmap changes
+	if err := common.SetConfigHash(r.client, instance.Namespace, &desired.Spec.Template); err != nil {
+		reqLogger.Error(err, "Failed to hash the mounted configmaps", "StatefulSet.Namespace", desired.Namespace, "StatefulSet.Name", desired.Name)
 		return err
-	} else if err := r.updateMustGatherServiceStatefulSet(instance, current, desired); err != nil {
+	}
+	// Create the StatefulSet, or update it if it drifted from the desired state
+	current := &appsv1.StatefulSet{}
+	result, err := common.CreateOrUpdate(r.client, instance, desired, current, func() runtime.Object {
+		return r.updatedMustGatherServiceStatefulSet(instance, current, desired)
+	})
+	if err != nil {
+		reqLogger.Error(err, "Failed to create or update StatefulSet", "StatefulSet.Namespace", desired.Namespace, "StatefulSet.Name", desired.Name)
 		return err
 	}
+	if result != controllerutil.OperationResultNone {
+		reqLogger.Info("StatefulSet "+string(result), "StatefulSet.Namespace", desired.Namespace, "StatefulSet.Name", desired.Name)
+	}
 
-	// Update the MustGatherService status with the pod names
+	// Record the pod states in the MustGatherService status
 	podList := &corev1.PodList{}
 	listOpts := []client.ListOption{
 		client.InNamespace(instance.Namespace),
-		client.MatchingLabels(labelsForMustGatherService(mustGatherResourceName, instance.Name)),
+		client.MatchingLabels(common.LabelsForMustGatherService(desired.Name, instance.Name)),
 	}
 	if err = r.client.List(context.TODO(), podList, listOpts...); err != nil {
 		reqLogger.Error(err, "Failed to list pods", "instance.Namespace", instance.Namespace, "instance.Name", instance.Name)
 		return err
 	}
-	podNames := common.GetPodNames(podList.Items)
-
-	// Update status.MustGatherServiceNodes if needed
-	if !reflect.DeepEqual(podNames, instance.Status.MustGatherServiceNodes) {
-		instance.Status.MustGatherServiceNodes = podNames
-		err := r.client.Status().Update(context.TODO(), instance)
-		if err != nil {
-			reqLogger.Error(err, "Failed to update MustGatherService status")
-			return err
-		}
-	}
+	// The status is written once at the end of the reconcile
+	instance.Status.MustGatherServiceNodes = common.GetPodNames(podList.Items)
+	instance.Status.MustGatherServicePods = common.PodStatuses(podList.Items)
+	instance.Status.MustGatherServiceImage = desired.Spec.Template.Spec.Containers[0].Image
 
 	return nil
 }
 
-func (r *ReconcileMustGatherService) updateMustGatherServiceStatefulSet(instance *operatorv1alpha1.MustGatherService,
-	current, desired *appsv1.StatefulSet) error {
+// updatedMustGatherServiceStatefulSet returns a copy of current in which the fields managed by the operator are set from desired
+func (r *ReconcileMustGatherService) updatedMustGatherServiceStatefulSet(instance *operatorv1alpha1.MustGatherService,
+	current, desired *appsv1.StatefulSet) *appsv1.StatefulSet {
 	reqLogger := log.WithValues("StatefulSet.Namespace", current.Namespace, "StatefulSet.Name", current.Name)
 
 	updated := current.DeepCopy()
 	updated.Spec.Replicas = desired.Spec.Replicas
+	updated.Spec.Template.ObjectMeta.Annotations = desired.Spec.Template.ObjectMeta.Annotations
 	updated.Spec.Template.Spec.Containers = desired.Spec.Template.Spec.Containers
 	updated.Spec.Template.Spec.Volumes = desired.Spec.Template.Spec.Volumes
 
-	reqLogger.Info("Updating StatefulSet")
 	// Set MustGatherService instance as the owner and controller
 	if err := controllerutil.SetControllerReference(instance, updated, r.scheme); err != nil {
 		reqLogger.Error(err, "SetControllerReference failed", "StatefulSet.Namespace", updated.Namespace, "StatefulSet.Name", updated.Name)
 	}
 
-	if err := r.client.Update(context.TODO(), updated); err != nil {
-		reqLogger.Error(err, "Failed to update StatefulSet", "StatefulSet.Namespace", updated.Namespace, "StatefulSet.Name", updated.Name)
-		return err
-	}
-
-	return nil
+	return updated
 }
 
 func (r *ReconcileMustGatherService) desiredMustGatherServiceStatefulset(instance *operatorv1alpha1.MustGatherService) *appsv1.StatefulSet {
-	appName := "must-gather-service"
-	labels := labelsForMustGatherService(appName, instance.Name)
+	appName := common.MustGatherResourceName(instance)
+	labels := common.LabelsForMustGatherService(appName, instance.Name)
 	annotations := annotationsForMustGatherService()
 
 	serviceAccountName := "ibm-healthcheck-operator"
@@ -143,6 +139,7 @@ func (r *ReconcileMustGatherService) desiredMustGatherServiceStatefulset(instanc
 	reqLogger.Info("Building MustGatherService StatefulSet", "StatefulSet.Namespace", instance.Namespace, "StatefulSet.Name", appName)
 
 	appResources := common.GetResources(&instance.Spec.MustGather.Resources)
+	image, pullPolicy := common.ResolveImage(&instance.Spec.MustGather.Image, constant.MustGatherServiceImageEnv, constant.DefaultMustGatherServiceImage)
 	appReplicas := int32(1)
 	if instance.Spec.MustGather.Replicas > 0 {
 		appReplicas = instance.Spec.MustGather.Replicas
@@ -173,8 +170,8 @@ func (r *ReconcileMustGatherService) desiredMustGatherServiceStatefulset(instanc
 					Containers: []corev1.Container{
 						{
 							Name:            appName,
-							Image:           os.Getenv("MUST_GATHER_SERVICE_IMAGE"),
-							ImagePullPolicy: corev1.PullIfNotPresent,
+							Image:           image,
+							ImagePullPolicy: pullPolicy,
 							Command:         defaultCommand,
 							SecurityContext: &commonSecurityContext,
 							Env: []corev1.EnvVar{
@@ -263,31 +260,28 @@ func (r *ReconcileMustGatherService) desiredMustGatherServiceStatefulset(instanc
 }
 
 func (r *ReconcileMustGatherService) createOrUpdateMustGatherServiceService(instance *operatorv1alpha1.MustGatherService) error {
-	appName := mustGatherResourceName
 	reqLogger := log.WithValues("MustGatherService.Namespace", instance.Namespace, "MustGatherService.Name", instance.Name)
 
 	// Define a new service
 	desired := r.desiredMustGatherServiceService(instance)
-	// Check if the service already exists, if not create a new one
+	// Create the service, or update it if it drifted from the desired state
 	current := &corev1.Service{}
-	err := r.client.Get(context.TODO(), types.NamespacedName{Name: appName, Namespace: instance.Namespace}, current)
-	if err != nil && errors.IsNotFound(err) {
-		reqLogger.Info("Creating a new Service", "Service.Namespace", desired.Namespace, "Service.Name", desired.Name)
-		if err := r.client.Create(context.TODO(), desired); err != nil {
-			reqLogger.Error(err, "Failed to create new Service", "Service.Namespace", desired.Namespace, "Service.Name", desired.Name)
-			return err
-		}
-	} else if err != nil {
-		reqLogger.Error(err, "Failed to get Service", "Service.Namespace", current.Namespace, "Service.Name", current.Name)
-		return err
-	} else if err := r.updateMustGatherServiceService(instance, current, desired); err != nil {
+	result, err := common.CreateOrUpdate(r.client, instance, desired, current, func() runtime.Object {
+		return r.updatedMustGatherServiceService(instance, current, desired)
+	})
+	if err != nil {
+		reqLogger.Error(err, "Failed to create or update Service", "Service.Namespace", desired.Namespace, "Service.Name", desired.Name)
 		return err
 	}
+	if result != controllerutil.OperationResultNone {
+		reqLogger.Info("Service "+string(result), "Service.Namespace", desired.Namespace, "Service.Name", desired.Name)
+	}
 
 	return nil
 }
 
-func (r *ReconcileMustGatherService) updateMustGatherServiceService(instance *operatorv1alpha1.MustGatherService, current, desired *corev1.Service) error {
+// updatedMustGatherServiceService returns a copy of current in which the fields managed by the operator are set from desired
+func (r *ReconcileMustGatherService) updatedMustGatherServiceService(instance *operatorv1alpha1.MustGatherService, current, desired *corev1.Service) *corev1.Service {
 	reqLogger := log.WithValues("Service.Namespace", current.Namespace, "Service.Name", current.Name)
 
 	updated := current.DeepCopy()
@@ -296,23 +290,17 @@ func (r *ReconcileMustGatherService) updateMustGatherServiceService(instance *op
 	updated.Spec.Selector = desired.Spec.Selector
 	updated.Spec.Type = desired.Spec.Type
 
-	reqLogger.Info("Updating Service")
 	// Set MustGatherService instance as the owner and controller
 	if err := controllerutil.SetControllerReference(instance, updated, r.scheme); err != nil {
 		reqLogger.Error(err, "SetControllerReference failed", "Service.Namespace", updated.Namespace, "Service.Name", updated.Name)
 	}
 
-	if err := r.client.Update(context.TODO(), updated); err != nil {
-		reqLogger.Error(err, "Failed to update Service", "Service.Namespace", updated.Namespace, "Service.Name", updated.Name)
-		return err
-	}
-
-	return nil
+	return updated
 }
 
 func (r *ReconcileMustGatherService) desiredMustGatherServiceService(instance *operatorv1alpha1.MustGatherService) *corev1.Service {
-	appName := mustGatherResourceName
-	labels := labelsForMustGatherService(appName, instance.Name)
+	appName := common.MustGatherResourceName(instance)
+	labels := common.LabelsForMustGatherService(appName, instance.Name)
 
 	reqLogger := log.WithValues("MustGatherService.Namespace", instance.Namespace, "MustGatherService.Name", instance.Name)
 	reqLogger.Info("Building MustGatherService Service", "Service.Namespace", instance.Namespace, "Service.Name", appName)
@@ -345,7 +333,7 @@ func (r *ReconcileMustGatherService) desiredMustGatherServiceService(instance *o
 }
 
 func (r *ReconcileMustGatherService) createOrUpdateMustGatherServiceConfigmap(instance *operatorv1alpha1.MustGatherService) error {
-	appName := mustGatherResourceName
+	appName := common.MustGatherResourceName(instance)
 	reqLogger := log.WithValues("MustGatherService.Namespace", instance.Namespace, "MustGatherService.Name", instance.Name)
 	labels := labelsForMustGatherServiceCustomCM(appName, instance.Name)
 
@@ -393,60 +381,120 @@ func (r *ReconcileMustGatherService) createOrUpdateMustGatherServiceConfigmap(in
 	return nil
 }
 
+// createOrUpdateMustGatherServiceExposure exposes the must gather service with an Ingress, a Route or an HTTPRoute
+// depending on the exposure mode, and deletes the objects of the other modes
+func (r *ReconcileMustGatherService) createOrUpdateMustGatherServiceExposure(instance *operatorv1alpha1.MustGatherService) error {
+	name := common.MustGatherResourceName(instance)
+	reqLogger := log.WithValues("MustGatherService.Namespace", instance.Namespace, "MustGatherService.Name", instance.Name)
+
+	mode, err := common.ResolveExposureMode(r.mapper, instance.Spec.Exposure.Mode)
+	if err != nil {
+		reqLogger.Error(err, "Failed to resolve exposure mode")
+		return err
+	}
+
+	if mode == operatorv1alpha1.ExposureIngress {
+		if err := r.createOrUpdateMustGatherServiceIngress(instance); err != nil {
+			return err
+		}
+	} else if err := r.deleteMustGatherServiceIngress(instance); err != nil {
+		return err
+	}
+
+	target := common.ExposureTarget{
+		Name:      name,
+		Namespace: instance.Namespace,
+		Labels:    common.LabelsForMustGatherService(name, instance.Name),
+		Path:      constant.MustgatherServiceRoute,
+		Port:      constant.MustgatherServicePort,
+	}
+	if instance.Spec.Ingress.Path != "" {
+		target.Path = instance.Spec.Ingress.Path
+	}
+
+	if err := common.ReconcileExposure(r.client, r.mapper, r.scheme, instance, mode, &instance.Spec.Exposure, target); err != nil {
+		reqLogger.Error(err, "Failed to reconcile the Route or HTTPRoute")
+		return err
+	}
+
+	return nil
+}
+
 func (r *ReconcileMustGatherService) createOrUpdateMustGatherServiceIngress(instance *operatorv1alpha1.MustGatherService) error {
-	appName := mustGatherResourceName
 	reqLogger := log.WithValues("MustGatherService.Namespace", instance.Namespace, "MustGatherService.Name", instance.Name)
 
+	if instance.Spec.Ingress.Disabled {
+		return r.deleteMustGatherServiceIngress(instance)
+	}
+
 	// Define a new ingress
 	desired := r.desiredMustGatherServiceIngress(instance)
-	// Check if the ingress already exists, if not create a new one
+	// Create the ingress, or update it if it drifted from the desired state
 	current := &networkingv1.Ingress{}
-	err := r.client.Get(context.TODO(), types.NamespacedName{Name: appName, Namespace: instance.Namespace}, current)
-	if err != nil && errors.IsNotFound(err) {
-		reqLogger.Info("Creating a new Ingress", "Ingress.Namespace", desired.Namespace, "Ingress.Name", desired.Name)
-		if err := r.client.Create(context.TODO(), desired); err != nil {
-			reqLogger.Error(err, "Failed to create new Ingress", "Ingress.Namespace", desired.Namespace, "Ingress.Name", desired.Name)
-			return err
-		}
-	} else if err != nil {
-		reqLogger.Error(err, "Failed to get Ingress", "Ingress.Namespace", current.Namespace, "Ingress.Name", current.Name)
-		return err
-	} else if err := r.updateMustGatherServiceIngress(instance, current, desired); err != nil {
+	result, err := common.CreateOrUpdate(r.client, instance, desired, current, func() runtime.Object {
+		return r.updatedMustGatherServiceIngress(instance, current, desired)
+	})
+	if err != nil {
+		reqLogger.Error(err, "Failed to create or update Ingress", "Ingress.Namespace", desired.Namespace, "Ingress.Name", desired.Name)
 		return err
 	}
+	if result != controllerutil.OperationResultNone {
+		reqLogger.Info("Ingress "+string(result), "Ingress.Namespace", desired.Namespace, "Ingress.Name", desired.Name)
+	}
 
 	return nil
 }
 
-func (r *ReconcileMustGatherService) updateMustGatherServiceIngress(instance *operatorv1alpha1.MustGatherService,
-	current, desired *networkingv1.Ingress) error {
+// updatedMustGatherServiceIngress returns a copy of current in which the fields managed by the operator are set from desired
+func (r *ReconcileMustGatherService) updatedMustGatherServiceIngress(instance *operatorv1alpha1.MustGatherService,
+	current, desired *networkingv1.Ingress) *networkingv1.Ingress {
 	reqLogger := log.WithValues("Ingress.Namespace", current.Namespace, "Ingress.Name", current.Name)
 
 	updated := current.DeepCopy()
 	updated.ObjectMeta.Labels = desired.ObjectMeta.Labels
 	updated.ObjectMeta.Annotations = desired.ObjectMeta.Annotations
+	updated.Spec.IngressClassName = desired.Spec.IngressClassName
 	updated.Spec.Rules = desired.Spec.Rules
+	updated.Spec.TLS = desired.Spec.TLS
 
-	reqLogger.Info("Updating Ingress")
 	// Set MustGatherService instance as the owner and controller
 	if err := controllerutil.SetControllerReference(instance, updated, r.scheme); err != nil {
 		reqLogger.Error(err, "SetControllerReference failed", "Ingress.Namespace", updated.Namespace, "Ingress.Name", updated.Name)
 	}
 
-	if err := r.client.Update(context.TODO(), updated); err != nil {
-		reqLogger.Error(err, "Failed to update Ingress", "Ingress.Namespace", updated.Namespace, "Ingress.Name", updated.Name)
+	return updated
+}
+
+// deleteMustGatherServiceIngress deletes the Ingress when it is disabled
+func (r *ReconcileMustGatherService) deleteMustGatherServiceIngress(instance *operatorv1alpha1.MustGatherService) error {
+	reqLogger := log.WithValues("MustGatherService.Namespace", instance.Namespace, "MustGatherService.Name", instance.Name)
+
+	current := &networkingv1.Ingress{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: common.MustGatherResourceName(instance), Namespace: instance.Namespace}, current)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		reqLogger.Error(err, "Failed to get Ingress", "Ingress.Namespace", current.Namespace, "Ingress.Name", current.Name)
+		return err
+	}
+	if !metav1.IsControlledBy(current, instance) {
+		return nil
+	}
+
+	reqLogger.Info("Deleting disabled Ingress", "Ingress.Namespace", current.Namespace, "Ingress.Name", current.Name)
+	if err := r.client.Delete(context.TODO(), current); err != nil && !errors.IsNotFound(err) {
+		reqLogger.Error(err, "Failed to delete Ingress", "Ingress.Namespace", current.Namespace, "Ingress.Name", current.Name)
 		return err
 	}
 
 	return nil
-
 }
 
 func (r *ReconcileMustGatherService) desiredMustGatherServiceIngress(instance *operatorv1alpha1.MustGatherService) *networkingv1.Ingress {
-	appName := mustGatherResourceName
-	labels := labelsForMustGatherService(appName, instance.Name)
-	annotations := annotationsForMustGatherServiceIngress()
-	pathType := networkingv1.PathType(constant.IngPathType)
+	appName := common.MustGatherResourceName(instance)
+	labels := common.LabelsForMustGatherService(appName, instance.Name)
+	annotations := common.IngressAnnotations(&instance.Spec.Ingress, annotationsForMustGatherServiceIngress())
 
 	reqLogger := log.WithValues("MustGatherService.Namespace", instance.Namespace, "MustGatherService.Name", instance.Name)
 	reqLogger.Info("Building MustGatherService Ingress", "Ingress.Namespace", instance.Namespace, "Ingress.Name", appName)
@@ -458,28 +506,7 @@ func (r *ReconcileMustGatherService) desiredMustGatherServiceIngress(instance *o
 			Labels:      labels,
 			Annotations: annotations,
 		},
-		Spec: networkingv1.IngressSpec{
-			Rules: []networkingv1.IngressRule{
-				{
-					IngressRuleValue: networkingv1.IngressRuleValue{
-						HTTP: &networkingv1.HTTPIngressRuleValue{
-							Paths: []networkingv1.HTTPIngressPath{
-								{
-									Path:     constant.MustgatherServiceRoute,
-									PathType: &pathType,
-									Backend: networkingv1.IngressBackend{
-										Service: &networkingv1.IngressServiceBackend{
-											Name: appName,
-											Port: networkingv1.ServiceBackendPort{Number: constant.MustgatherServicePort},
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-		},
+		Spec: common.IngressSpec(&instance.Spec.Ingress, constant.MustgatherServiceRoute, appName, constant.MustgatherServicePort),
 	}
 
 	// Set MustGatherService instance as the owner and controller
@@ -494,46 +521,80 @@ func (r *ReconcileMustGatherService) createOrUpdateMustGatherServicePVC(instance
 	pvcName := instance.Spec.PersistentVolumeClaim.Name
 	reqLogger := log.WithValues("MustGatherService.Namespace", instance.Namespace, "MustGatherService.Name", instance.Name)
 
-	// Define must gather persistence storage
-	desired := r.desiredMustGatherServicePVC(instance)
-
 	// Check if this pvc already exists
 	current := &corev1.PersistentVolumeClaim{}
 	err := r.client.Get(context.TODO(), types.NamespacedName{Name: pvcName, Namespace: instance.Namespace}, current)
 
 	if err != nil && errors.IsNotFound(err) {
+		// Define must gather persistence storage
+		desired, err := r.desiredMustGatherServicePVC(instance)
+		if err != nil {
+			reqLogger.Error(err, "Failed to get the storage class of the pvc", "pvc.Namespace", instance.Namespace, "pvc.Name", pvcName)
+			var scErr *storageClassError
+			if goerrors.As(err, &scErr) {
+				setPVCNotCreatedStatus(&instance.Status, instance.Generation, scErr)
+			}
+			return err
+		}
 		reqLogger.Info("Creating a new pvc", "pvc.Namespace", desired.Namespace, "pvc.Name", desired.Name)
 		err = r.client.Create(context.TODO(), desired)
 		if err != nil {
 			return err
 		}
-		// pvc created successfully - don't requeue
+		// The status is written once at the end of the reconcile
+		setPVCStatus(&instance.Status, instance.Generation, desired, nil)
 		return nil
 	} else if err != nil {
 		reqLogger.Error(err, "Failed to get pvc", "pvc.Namespace", current.Namespace, "pvc.Name", current.Name)
 		return err
-	} else {
-		// pvc already exists - don't requeue
-		reqLogger.Info("Skip reconcile: pvc already exists", "pvc.Namespace", current.Namespace, "pvc.Name", current.Name)
-		return nil
 	}
+
+	// The storage class and access modes of a pvc can't change, only its storage request can grow
+	err = r.expandMustGatherServicePVC(instance, current)
+	var expansionErr *expansionError
+	if err != nil && !goerrors.As(err, &expansionErr) {
+		reqLogger.Error(err, "Failed to expand pvc", "pvc.Namespace", current.Namespace, "pvc.Name", current.Name)
+		return err
+	}
+	if expansionErr != nil {
+		reqLogger.Info("Skip pvc expansion: "+expansionErr.Error(), "pvc.Namespace", current.Namespace, "pvc.Name", current.Name)
+	}
+	setPVCStatus(&instance.Status, instance.Generation, current, expansionErr)
+	return nil
 }
 
 // newMustGatherPVC create a pvc for must gather service
-func (r *ReconcileMustGatherService) desiredMustGatherServicePVC(instance *operatorv1alpha1.MustGatherService) *corev1.PersistentVolumeClaim {
+func (r *ReconcileMustGatherService) desiredMustGatherServicePVC(instance *operatorv1alpha1.MustGatherService) (*corev1.PersistentVolumeClaim, error) {
 	var storageClassName string
 	var storageRequest resource.Quantity
+	var readWriteMany bool
+	var err error
 
 	reqLogger := log.WithValues("MustGatherService.Namespace", instance.Namespace, "MustGatherService.Name", instance.Name)
 	reqLogger.Info("Building MustGatherService PVC", "PVC.Namespace", instance.Namespace, "PVC.Name", instance.Spec.PersistentVolumeClaim.Name)
 
-	if instance.Spec.PersistentVolumeClaim.StorageClassName != "" {
-		storageClassName = instance.Spec.PersistentVolumeClaim.StorageClassName
+	spec := &instance.Spec.PersistentVolumeClaim
+	if spec.StorageClassName != "" {
+		storageClassName = spec.StorageClassName
+		if spec.AccessMode == "" {
+			readWriteMany, err = r.readWriteManyStorageClass(spec, storageClassName)
+		}
 	} else {
-		storageClassName = r.getDefaultStorageClass()
+		storageClassName, readWriteMany, err = r.getDefaultStorageClass(spec)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	accessMode := spec.AccessMode
+	if accessMode == "" {
+		accessMode = corev1.ReadWriteOnce
+		if readWriteMany {
+			accessMode = corev1.ReadWriteMany
+		}
 	}
 
-	if val, ok := instance.Spec.PersistentVolumeClaim.Resources.Requests[corev1.ResourceStorage]; ok {
+	if val, ok := spec.Resources.Requests[corev1.ResourceStorage]; ok {
 		storageRequest = val
 	} else {
 		storageRequest = resource.MustParse("2Gi")
@@ -541,72 +602,91 @@ func (r *ReconcileMustGatherService) desiredMustGatherServicePVC(instance *opera
 
 	pvc := &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        instance.Spec.PersistentVolumeClaim.Name,
+			Name:        spec.Name,
 			Namespace:   instance.Namespace,
-			Labels:      labelsForMustGatherService(instance.Spec.PersistentVolumeClaim.Name, instance.Name),
+			Labels:      common.LabelsForMustGatherService(spec.Name, instance.Name),
 			Annotations: annotationsForMustGatherService(),
 		},
 		Spec: corev1.PersistentVolumeClaimSpec{
-			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			AccessModes: []corev1.PersistentVolumeAccessMode{accessMode},
 			Resources: corev1.ResourceRequirements{
 				Requests: corev1.ResourceList{
 					corev1.ResourceStorage: storageRequest,
 				},
 			},
-			StorageClassName: &storageClassName,
 		},
 	}
+	// Without a storage class the pvc gets the default class of the cluster
+	if storageClassName != "" {
+		pvc.Spec.StorageClassName = &storageClassName
+	}
 	// Set MustGatherService instance as the owner and controller
 	if err := controllerutil.SetControllerReference(instance, pvc, r.scheme); err != nil {
-		reqLogger.Error(err, "SetControllerReference failed", "pvc.Namespace", instance.Namespace, "pvc.Name", instance.Spec.PersistentVolumeClaim.Name)
+		reqLogger.Error(err, "SetControllerReference failed", "pvc.Namespace", instance.Namespace, "pvc.Name", spec.Name)
 	}
 
-	return pvc
+	return pvc, nil
 }
 
-func (r *ReconcileMustGatherService) getDefaultStorageClass() string {
+// getDefaultStorageClass returns the storage class selected for the pvc among the classes of the
+// allowed provisioners, and whether the pvc is ReadWriteMany. The name is empty when the cluster has no
+// class to select, the pvc then gets the default class of the cluster. It is a storageClassError when
+// the allowed provisioners match no class.
+func (r *ReconcileMustGatherService) getDefaultStorageClass(pvc *operatorv1alpha1.PersistentVolumeClaim) (string, bool, error) {
 	scList := &storagev1.StorageClassList{}
-	err := r.reader.List(context.TODO(), scList)
-	if err != nil {
-		return ""
-	}
-	if len(scList.Items) == 0 {
-		return ""
+	if err := r.reader.List(context.TODO(), scList); err != nil {
+		return "", false, err
 	}
 
 	var defaultSC []string
 	var nonDefaultSC []string
+	var defaultRWXSC []string
+	var nonDefaultRWXSC []string
 
 	for _, sc := range scList.Items {
-		if sc.Provisioner == "kubernetes.io/no-provisioner" {
+		if sc.Provisioner == "kubernetes.io/no-provisioner" || !allowedProvisioner(pvc, sc.Provisioner) {
 			continue
 		}
-		if sc.ObjectMeta.GetAnnotations()["storageclass.kubernetes.io/is-default-class"] == "true" {
+		isDefault := sc.ObjectMeta.GetAnnotations()["storageclass.kubernetes.io/is-default-class"] == "true"
+		if isDefault {
 			defaultSC = append(defaultSC, sc.GetName())
+		} else {
+			nonDefaultSC = append(nonDefaultSC, sc.GetName())
+		}
+		if !readWriteManyProvisioner(sc.Provisioner) {
 			continue
 		}
-		nonDefaultSC = append(nonDefaultSC, sc.GetName())
+		if isDefault {
+			defaultRWXSC = append(defaultRWXSC, sc.GetName())
+		} else {
+			nonDefaultRWXSC = append(nonDefaultRWXSC, sc.GetName())
+		}
+	}
+
+	if pvc.StorageClassSelection == operatorv1alpha1.StorageClassPreferReadWriteMany || pvc.AccessMode == corev1.ReadWriteMany {
+		if len(defaultRWXSC) != 0 {
+			return defaultRWXSC[0], true, nil
+		}
+		if len(nonDefaultRWXSC) != 0 {
+			return nonDefaultRWXSC[0], true, nil
+		}
 	}
 
 	if len(defaultSC) != 0 {
-		return defaultSC[0]
+		return defaultSC[0], false, nil
 	}
 
 	if len(nonDefaultSC) != 0 {
-		return nonDefaultSC[0]
+		return nonDefaultSC[0], false, nil
 	}
 
-	return ""
-}
-
-func labelsForMustGatherService(name string, releaseName string) map[string]string {
-	return map[string]string{
-		"app":                          name,
-		"release":                      releaseName,
-		"app.kubernetes.io/name":       name,
-		"app.kubernetes.io/instance":   releaseName,
-		"app.kubernetes.io/managed-by": "",
+	if len(pvc.AllowedProvisioners) != 0 {
+		return "", false, &storageClassError{
+			reason: "NoMatchingStorageClass",
+			err:    fmt.Errorf("no storage class of the allowed provisioners %s", strings.Join(pvc.AllowedProvisioners, ", ")),
+		}
 	}
+	return "", false, nil
 }
 
 func labelsForMustGatherServiceCustomCM(name string, releaseName string) map[string]string {
@@ -17,14 +17,20 @@ package mustgatherservice
 
 import (
 	"context"
+	"time"
 
 	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
 
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -49,7 +55,7 @@ func Add(mgr manager.Manager) error {
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileMustGatherService{client: mgr.GetClient(), reader: mgr.GetAPIReader(), scheme: mgr.GetScheme()}
+	return &ReconcileMustGatherService{client: mgr.GetClient(), reader: mgr.GetAPIReader(), mapper: mgr.GetRESTMapper(), scheme: mgr.GetScheme()}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -76,9 +82,8 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
-	// TODO(user): Modify this to be the types you create that are owned by the primary resource
-	// Watch for changes to secondary resource Pods and requeue the owner MustGatherService
-	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestForOwner{
+	// PersistentVolumeClaim, its expansion is reported in the status
+	err = c.Watch(&source.Kind{Type: &corev1.PersistentVolumeClaim{}}, &handler.EnqueueRequestForOwner{
 		IsController: true,
 		OwnerType:    &operatorv1alpha1.MustGatherService{},
 	})
@@ -86,6 +91,24 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Job, the prune job reports its result when it finishes
+	err = c.Watch(&source.Kind{Type: &batchv1.Job{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &operatorv1alpha1.MustGatherService{},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Pods are owned by the StatefulSet, they are mapped to the MustGatherService which owns the StatefulSet
+	// so that the pod states in the status are refreshed
+	err = c.Watch(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: common.OperandPodRequests(mgr.GetClient(), "MustGatherService"),
+	})
+	if err != nil {
+		return err
+	}
+
 	// Ingress
 	err = c.Watch(&source.Kind{Type: &networkingv1.Ingress{}}, &handler.EnqueueRequestForOwner{
 		IsController: true,
@@ -104,9 +127,52 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// The registry mirrors apply to the images of every MustGatherService
+	err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			if !common.IsRegistryMirrors(a.Meta) {
+				return nil
+			}
+			return mustGatherServiceRequests(mgr.GetClient())
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Route and HTTPRoute, when served by the cluster
+	for _, gvk := range common.ServedExposureKinds(mgr.GetRESTMapper()) {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		err = c.Watch(&source.Kind{Type: u}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &operatorv1alpha1.MustGatherService{},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// mustGatherServiceRequests returns a request for every MustGatherService watched by the operator
+func mustGatherServiceRequests(c client.Client) []reconcile.Request {
+	mustGatherServiceList := &operatorv1alpha1.MustGatherServiceList{}
+	if err := c.List(context.TODO(), mustGatherServiceList); err != nil {
+		log.Error(err, "Failed to list MustGatherServices")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, m := range mustGatherServiceList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: m.Namespace, Name: m.Name},
+		})
+	}
+	return requests
+}
+
 // blank assignment to verify that ReconcileMustGatherService implements reconcile.Reconciler
 var _ reconcile.Reconciler = &ReconcileMustGatherService{}
 
@@ -116,6 +182,7 @@ type ReconcileMustGatherService struct {
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
 	reader client.Reader
+	mapper meta.RESTMapper
 	scheme *runtime.Scheme
 }
 
@@ -144,30 +211,70 @@ func (r *ReconcileMustGatherService) Reconcile(request reconcile.Request) (recon
 		return reconcile.Result{}, err
 	}
 
-	if err = r.createOrUpdateMustGatherServicePVC(instance); err != nil {
-		reqLogger.Error(err, "Failed to create or update PVC for mustgather service")
+	// The name of the operands is decided before they are created, and kept
+	if err := common.RecordMustGatherResourceName(r.client, instance); err != nil {
+		reqLogger.Error(err, "Failed to record the name of the operands")
 		return reconcile.Result{}, err
 	}
 
-	if err = r.createOrUpdateMustGatherServiceStatefulSet(instance); err != nil {
-		reqLogger.Error(err, "Failed to create or update StatefulSet for mustgather service")
+	// The status is gathered in instance and written once, as a patch from original
+	original := instance.DeepCopy()
+	reconcileErr := r.reconcileOperands(instance)
+	setExposureStatus(&instance.Status, instance.Generation, common.GetExposureState(r.client, r.mapper,
+		&instance.Spec.Exposure, &instance.Spec.Ingress, common.MustGatherResourceName(instance), instance.Namespace))
+
+	if err = r.updateMustGatherServiceStatus(original, instance); err != nil {
 		return reconcile.Result{}, err
 	}
+	if reconcileErr != nil {
+		return reconcile.Result{}, reconcileErr
+	}
+
+	// Requeue for the next prune
+	if next := instance.Status.Retention.NextPruneTime; next != nil {
+		after := time.Until(next.Time)
+		if after < time.Second {
+			after = time.Second
+		}
+		return reconcile.Result{RequeueAfter: after}, nil
+	}
+
+	return reconcile.Result{}, nil
+}
 
-	if err = r.createOrUpdateMustGatherServiceService(instance); err != nil {
+// reconcileOperands creates or updates all the resources managed by the MustGatherService
+func (r *ReconcileMustGatherService) reconcileOperands(instance *operatorv1alpha1.MustGatherService) error {
+	reqLogger := log.WithValues("Request.Namespace", instance.Namespace, "Request.Name", instance.Name)
+
+	if err := r.createOrUpdateMustGatherServicePVC(instance); err != nil {
+		reqLogger.Error(err, "Failed to create or update PVC for mustgather service")
+		return err
+	}
+
+	if err := r.createOrUpdateMustGatherServiceStatefulSet(instance); err != nil {
+		reqLogger.Error(err, "Failed to create or update StatefulSet for mustgather service")
+		return err
+	}
+
+	if err := r.reconcileMustGatherServiceRetention(instance); err != nil {
+		reqLogger.Error(err, "Failed to prune the archives of mustgather service")
+		return err
+	}
+
+	if err := r.createOrUpdateMustGatherServiceService(instance); err != nil {
 		reqLogger.Error(err, "Failed to create or update Service for mustgather service")
-		return reconcile.Result{}, err
+		return err
 	}
 
-	if err = r.createOrUpdateMustGatherServiceIngress(instance); err != nil {
-		reqLogger.Error(err, "Failed to create or update Ingress for mustgather service")
-		return reconcile.Result{}, err
+	if err := r.createOrUpdateMustGatherServiceExposure(instance); err != nil {
+		reqLogger.Error(err, "Failed to create or update Ingress, Route or HTTPRoute for mustgather service")
+		return err
 	}
 
-	if err = r.createOrUpdateMustGatherServiceConfigmap(instance); err != nil {
+	if err := r.createOrUpdateMustGatherServiceConfigmap(instance); err != nil {
 		reqLogger.Error(err, "Failed to create or update default custom configmap for mustgather service")
-		return reconcile.Result{}, err
+		return err
 	}
 
-	return reconcile.Result{}, nil
+	return nil
 }
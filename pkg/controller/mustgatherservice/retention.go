@@ -0,0 +1,426 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherservice
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	"github.com/IBM/ibm-healthcheck-operator/pkg/artifact"
+	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
+	constant "github.com/IBM/ibm-healthcheck-operator/pkg/controller/constant"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// defaultPruneInterval is the interval between two prunes when the retention doesn't set it
+const defaultPruneInterval = time.Hour
+
+// pruneScript deletes the oldest archives of the must gather pvc beyond the retention limits, except
+// the archives of the running gathers. The usage left is written in the termination message.
+const pruneScript = `set -u
+cd /must-gather || exit 1
+now=$(date +%s)
+
+# archives lists the archives, oldest first, as modification time, size in bytes and name. The signed
+# manifest of an archive is counted and pruned with the archive. The hidden entries are not archives, such
+# as the temporary archive being written by a sign job.
+archives() {
+	for a in *; do
+		[ -e "$a" ] || continue
+		[ "$a" = "lost+found" ] && continue
+		case "$a" in *.manifest.json | *.manifest.sig) [ -e "${a%.manifest.*}" ] && continue ;; esac
+		size=$(du -skc -- "$a" "$a".manifest.* 2>/dev/null | tail -n 1 | cut -f1)
+		printf '%s %s %s\n' "$(stat -c %Y "$a")" "$((size * 1024))" "$a"
+	done | sort -n
+}
+
+# active returns whether the archive is the data of a running gather
+active() {
+	for j in $ACTIVE_GATHERS; do
+		[ "$1" = "$j" ] && return 0
+	done
+	return 1
+}
+
+# expired returns whether the archive modified at $1 is beyond the retention limits
+expired() {
+	[ "$MAX_AGE_SECONDS" -gt 0 ] && [ $((now - $1)) -gt "$MAX_AGE_SECONDS" ] && return 0
+	[ "$MAX_COUNT" -ge 0 ] && [ "$count" -gt "$MAX_COUNT" ] && return 0
+	[ "$MAX_TOTAL_BYTES" -ge 0 ] && [ "$total" -gt "$MAX_TOTAL_BYTES" ] && return 0
+	return 1
+}
+
+list=$(archives)
+count=0
+total=0
+while read -r mtime size name; do
+	[ -n "$name" ] || continue
+	count=$((count + 1))
+	total=$((total + size))
+done <<ARCHIVES
+$list
+ARCHIVES
+
+pruned=0
+while read -r mtime size name; do
+	[ -n "$name" ] || continue
+	active "$name" && continue
+	expired "$mtime" || continue
+	echo "pruning $name"
+	rm -rf -- "$name" "$name.manifest.json" "$name.manifest.sig" || exit 1
+	count=$((count - 1))
+	total=$((total - size))
+	pruned=$((pruned + 1))
+done <<ARCHIVES
+$list
+ARCHIVES
+
+printf '{"usedBytes":%d,"archiveCount":%d,"prunedCount":%d}' "$total" "$count" "$pruned" > /dev/termination-log
+`
+
+// pruneTolerations are the tolerations of the must gather service pod, so that the prune pod can run on its node
+var pruneTolerations = []corev1.Toleration{
+	{
+		Key:      "dedicated",
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffectNoSchedule,
+	},
+	{
+		Key:      "CriticalAddonsOnly",
+		Operator: corev1.TolerationOpExists,
+	},
+}
+
+// pruneResult is the termination message of the prune pod
+type pruneResult struct {
+	UsedBytes    int64 `json:"usedBytes"`
+	ArchiveCount int32 `json:"archiveCount"`
+	PrunedCount  int32 `json:"prunedCount"`
+}
+
+// reconcileMustGatherServiceRetention runs a prune job at every prune interval while a retention
+// limit is set, and records its result in the status
+func (r *ReconcileMustGatherService) reconcileMustGatherServiceRetention(instance *operatorv1alpha1.MustGatherService) error {
+	reqLogger := log.WithValues("MustGatherService.Namespace", instance.Namespace, "MustGatherService.Name", instance.Name)
+	name := pruneJobName(common.MustGatherResourceName(instance))
+
+	job := &batchv1.Job{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: instance.Namespace}, job)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+	if found && !metav1.IsControlledBy(job, instance) {
+		reqLogger.Info("Skip prune: job exists and is not managed by the MustGatherService", "Job.Name", name)
+		return nil
+	}
+
+	if !retentionEnabled(&instance.Spec.Retention) {
+		instance.Status.Retention = operatorv1alpha1.RetentionStatus{}
+		meta.RemoveStatusCondition(&instance.Status.Conditions, operatorv1alpha1.ConditionPruned)
+		if found {
+			return r.deletePruneJob(job)
+		}
+		return nil
+	}
+
+	if found {
+		// the job is watched, the MustGatherService is reconciled again when it finishes
+		if !common.JobFinished(job) {
+			return nil
+		}
+		if err := r.setRetentionStatus(instance, job); err != nil {
+			return err
+		}
+		// the finished job is kept until the next prune, for its logs
+		if time.Now().Before(instance.Status.Retention.NextPruneTime.Time) {
+			return nil
+		}
+		return r.deletePruneJob(job)
+	}
+
+	if next := instance.Status.Retention.NextPruneTime; next != nil && time.Now().Before(next.Time) {
+		return nil
+	}
+
+	activeGathers, err := r.activeGathers(instance.Namespace)
+	if err != nil {
+		return err
+	}
+	desired := r.desiredMustGatherServicePruneJob(instance, name, activeGathers)
+	// Pull the images from their registry mirror
+	if err := common.SetMirroredImages(r.client, &desired.Spec.Template); err != nil {
+		return err
+	}
+	if err := controllerutil.SetControllerReference(instance, desired, r.scheme); err != nil {
+		return err
+	}
+	reqLogger.Info("Creating a new prune job", "Job.Namespace", desired.Namespace, "Job.Name", desired.Name)
+	if err := r.client.Create(context.TODO(), desired); err != nil {
+		return err
+	}
+	instance.Status.Retention.NextPruneTime = nil
+	return nil
+}
+
+// setRetentionStatus records the result of the finished prune job, and when the next prune starts
+func (r *ReconcileMustGatherService) setRetentionStatus(instance *operatorv1alpha1.MustGatherService, job *batchv1.Job) error {
+	pod, err := common.LatestJobPod(r.client, job)
+	if err != nil {
+		return err
+	}
+
+	pruned := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionPruned,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: instance.Generation,
+		Reason:             "Pruned",
+	}
+
+	result := &pruneResult{}
+	finished := job.Status.CompletionTime
+	if c := common.JobCondition(job, batchv1.JobFailed); c != nil {
+		finished = &c.LastTransitionTime
+		pruned.Status = metav1.ConditionFalse
+		pruned.Reason = "PruneFailed"
+		pruned.Message = fmt.Sprintf("job %s failed: %s", job.Name, c.Message)
+	} else if err := common.UnmarshalTerminationMessage(pod, result); err != nil {
+		pruned.Status = metav1.ConditionFalse
+		pruned.Reason = "InvalidPruneResult"
+		pruned.Message = fmt.Sprintf("job %s: %v", job.Name, err)
+	} else {
+		instance.Status.Retention.UsedStorage = resource.NewQuantity(result.UsedBytes, resource.BinarySI)
+		instance.Status.Retention.ArchiveCount = result.ArchiveCount
+		instance.Status.Retention.PrunedCount = result.PrunedCount
+		instance.Status.Retention.LastPruneTime = finished
+		pruned.Message = fmt.Sprintf("%d archives pruned, %d archives kept", result.PrunedCount, result.ArchiveCount)
+	}
+	if finished == nil {
+		now := metav1.Now()
+		finished = &now
+	}
+
+	next := metav1.NewTime(finished.Add(pruneInterval(&instance.Spec.Retention)))
+	instance.Status.Retention.NextPruneTime = &next
+	meta.SetStatusCondition(&instance.Status.Conditions, pruned)
+	return nil
+}
+
+// deletePruneJob deletes the prune job with its pods
+func (r *ReconcileMustGatherService) deletePruneJob(job *batchv1.Job) error {
+	log.Info("Deleting prune job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+	err := r.client.Delete(context.TODO(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// activeGathers returns the job names of the must gather jobs which are not finished, or not
+// redacted or uploaded yet, their archives are not pruned
+func (r *ReconcileMustGatherService) activeGathers(namespace string) ([]string, error) {
+	mgjList := &operatorv1alpha1.MustGatherJobList{}
+	if err := r.client.List(context.TODO(), mgjList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, mgj := range mgjList.Items {
+		switch mgj.Status.Phase {
+		case operatorv1alpha1.JobFailed, operatorv1alpha1.JobCancelled:
+			continue
+		case operatorv1alpha1.JobSucceeded:
+			// the archive of a succeeded gather is active until it is redacted and uploaded
+			if !postGatherActive(&mgj.Status) {
+				continue
+			}
+		}
+		if mgj.Status.JobName != "" {
+			names = append(names, mgj.Status.JobName)
+		}
+	}
+	return names, nil
+}
+
+// desiredMustGatherServicePruneJob returns the job which prunes the archives of the must gather pvc
+func (r *ReconcileMustGatherService) desiredMustGatherServicePruneJob(instance *operatorv1alpha1.MustGatherService,
+	name string, activeGathers []string) *batchv1.Job {
+	var backoffLimit = int32(1)
+	var activeDeadlineSeconds = int64(600)
+
+	appName := common.MustGatherResourceName(instance)
+	labels := common.LabelsForMustGatherService(name, instance.Name)
+	retention := &instance.Spec.Retention
+	// the data of a gather is named after its job only, a prefix would protect the data of other runs
+	activeArtifacts := []string{}
+	for _, job := range activeGathers {
+		activeArtifacts = append(activeArtifacts, artifact.Names(job)...)
+	}
+
+	maxAgeSeconds := int64(0)
+	if retention.MaxAge != nil {
+		maxAgeSeconds = int64(retention.MaxAge.Seconds())
+	}
+	maxCount := int64(-1)
+	if retention.MaxCount != nil {
+		maxCount = int64(*retention.MaxCount)
+	}
+	maxTotalBytes := int64(-1)
+	if retention.MaxTotalSize != nil {
+		maxTotalBytes = retention.MaxTotalSize.Value()
+	}
+
+	image, pullPolicy := common.ResolveImage(&operatorv1alpha1.Image{}, constant.MustGatherImageEnv, constant.DefaultMustGatherImage)
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: instance.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &activeDeadlineSeconds,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: annotationsForMustGatherService(),
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:                corev1.RestartPolicyNever,
+					ServiceAccountName:           "default",
+					AutomountServiceAccountToken: &falseVar,
+					NodeSelector:                 instance.Spec.MustGather.NodeSelector,
+					Tolerations:                  common.MergeTolerations(pruneTolerations, instance.Spec.MustGather.Tolerations),
+					Containers: []corev1.Container{
+						{
+							Name:            "prune",
+							Image:           image,
+							ImagePullPolicy: pullPolicy,
+							Command:         []string{"/bin/sh", "-c", pruneScript},
+							Env: []corev1.EnvVar{
+								{Name: "MAX_AGE_SECONDS", Value: strconv.FormatInt(maxAgeSeconds, 10)},
+								{Name: "MAX_COUNT", Value: strconv.FormatInt(maxCount, 10)},
+								{Name: "MAX_TOTAL_BYTES", Value: strconv.FormatInt(maxTotalBytes, 10)},
+								{Name: "ACTIVE_GATHERS", Value: strings.Join(activeArtifacts, " ")},
+							},
+							Resources: *common.GetResources(&operatorv1alpha1.Resources{}),
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "must-gather",
+									MountPath: "/must-gather",
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "must-gather",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: instance.Spec.PersistentVolumeClaim.Name,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// A ReadWriteOnce volume can only be mounted on the node of the must gather service pod
+	if !readWriteManyStatus(&instance.Status.PersistentVolumeClaim) {
+		job.Spec.Template.Spec.Affinity = &corev1.Affinity{
+			PodAffinity: &corev1.PodAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+					{
+						LabelSelector: &metav1.LabelSelector{
+							MatchLabels: common.LabelsForMustGatherService(appName, instance.Name),
+						},
+						TopologyKey: "kubernetes.io/hostname",
+					},
+				},
+			},
+		}
+	}
+
+	return job
+}
+
+// retentionEnabled returns whether a retention limit is set
+func retentionEnabled(retention *operatorv1alpha1.Retention) bool {
+	return retention.MaxAge != nil || retention.MaxCount != nil || retention.MaxTotalSize != nil
+}
+
+// pruneInterval returns the interval between two prunes
+func pruneInterval(retention *operatorv1alpha1.Retention) time.Duration {
+	if retention.PruneInterval != nil && retention.PruneInterval.Duration > 0 {
+		return retention.PruneInterval.Duration
+	}
+	return defaultPruneInterval
+}
+
+// pruneJobName returns the name of the prune job of the must gather service
+func pruneJobName(appName string) string {
+	suffix := "-prune"
+	// the job name is also a pod label value
+	if len(appName)+len(suffix) > validation.LabelValueMaxLength {
+		appName = strings.TrimRight(appName[:validation.LabelValueMaxLength-len(suffix)], "-.")
+	}
+	return appName + suffix
+}
+
+// postGatherActive returns whether the data of a succeeded gather is being redacted, signed or uploaded
+func postGatherActive(status *operatorv1alpha1.MustGatherJobStatus) bool {
+	if r := status.Redaction; r != nil && !jobPhaseFinished(r.Phase) {
+		return true
+	}
+	if i := status.Integrity; i != nil && !jobPhaseFinished(i.Phase) {
+		return true
+	}
+	if u := status.Upload; u != nil && !jobPhaseFinished(u.Phase) {
+		return true
+	}
+	return false
+}
+
+func jobPhaseFinished(phase operatorv1alpha1.JobPhase) bool {
+	return phase == operatorv1alpha1.JobSucceeded || phase == operatorv1alpha1.JobFailed
+}
+
+// readWriteManyStatus returns whether the pvc is ReadWriteMany
+func readWriteManyStatus(pvc *operatorv1alpha1.PersistentVolumeClaimStatus) bool {
+	for _, mode := range pvc.AccessModes {
+		if mode == corev1.ReadWriteMany {
+			return true
+		}
+	}
+	return false
+}
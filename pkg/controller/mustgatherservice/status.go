@@ -0,0 +1,153 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherservice
+
+import (
+	"fmt"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+	common "github.com/IBM/ibm-healthcheck-operator/pkg/controller/common"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// updateMustGatherServiceStatus writes the status gathered during the reconcile once, as a patch from original
+func (r *ReconcileMustGatherService) updateMustGatherServiceStatus(original, instance *operatorv1alpha1.MustGatherService) error {
+	reqLogger := log.WithValues("MustGatherService.Namespace", instance.Namespace, "MustGatherService.Name", instance.Name)
+
+	status := instance.Status.DeepCopy()
+	err := common.PatchStatus(r.client, r.reader, original, instance, func() {
+		original.DeepCopyInto(instance)
+		status.DeepCopyInto(&instance.Status)
+	})
+	if err != nil {
+		reqLogger.Error(err, "Failed to update MustGatherService status")
+		return err
+	}
+	return nil
+}
+
+// setPVCStatus sets the state of the pvc and its PVCCreated, Resizing and FileSystemResizePending conditions.
+// expansionErr is why the pvc is not expanded to the requested storage, if it isn't.
+func setPVCStatus(status *operatorv1alpha1.MustGatherServiceStatus, generation int64, pvc *corev1.PersistentVolumeClaim,
+	expansionErr *expansionError) {
+	status.PersistentVolumeClaim = operatorv1alpha1.PersistentVolumeClaimStatus{
+		StorageClassName: storageClassOf(pvc),
+		AccessModes:      pvc.Spec.AccessModes,
+	}
+	if len(pvc.Status.AccessModes) > 0 {
+		status.PersistentVolumeClaim.AccessModes = pvc.Status.AccessModes
+	}
+	requested, hasRequest := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if hasRequest {
+		status.PersistentVolumeClaim.Requested = &requested
+	}
+	capacity, hasCapacity := pvc.Status.Capacity[corev1.ResourceStorage]
+	if hasCapacity {
+		status.PersistentVolumeClaim.Capacity = &capacity
+	}
+
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               operatorv1alpha1.ConditionPVCCreated,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "Created",
+		Message:            "The pvc is created",
+	})
+
+	resizing := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionResizing,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "AsExpected",
+		Message:            "The pvc has the requested storage",
+	}
+	fileSystemResizePending := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionFileSystemResizePending,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "AsExpected",
+		Message:            "The file system of the volume has its full capacity",
+	}
+
+	if c := pvcCondition(pvc, corev1.PersistentVolumeClaimResizing); c != nil {
+		resizing.Status = metav1.ConditionTrue
+		resizing.Reason = "Resizing"
+		resizing.Message = fmt.Sprintf("The volume is being expanded to %s", requested.String())
+		if c.Message != "" {
+			resizing.Message = c.Message
+		}
+	} else if hasRequest && hasCapacity && capacity.Cmp(requested) < 0 {
+		resizing.Status = metav1.ConditionTrue
+		resizing.Reason = "ExpansionRequested"
+		resizing.Message = fmt.Sprintf("The volume is expanded from %s to %s", capacity.String(), requested.String())
+	} else if expansionErr != nil {
+		resizing.Reason = expansionErr.reason
+		resizing.Message = expansionErr.Error()
+	}
+
+	if c := pvcCondition(pvc, corev1.PersistentVolumeClaimFileSystemResizePending); c != nil {
+		fileSystemResizePending.Status = metav1.ConditionTrue
+		fileSystemResizePending.Reason = "FileSystemResizePending"
+		fileSystemResizePending.Message = "The file system is resized when the must gather service pod restarts"
+		if c.Message != "" {
+			fileSystemResizePending.Message = c.Message
+		}
+	}
+
+	meta.SetStatusCondition(&status.Conditions, resizing)
+	meta.SetStatusCondition(&status.Conditions, fileSystemResizePending)
+}
+
+// setPVCNotCreatedStatus sets the PVCCreated condition to false, scErr is why no storage class is selected
+func setPVCNotCreatedStatus(status *operatorv1alpha1.MustGatherServiceStatus, generation int64, scErr *storageClassError) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               operatorv1alpha1.ConditionPVCCreated,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             scErr.reason,
+		Message:            scErr.Error(),
+	})
+}
+
+// setExposureStatus sets the Exposed condition from the state of the Ingress, Route or HTTPRoute
+func setExposureStatus(status *operatorv1alpha1.MustGatherServiceStatus, generation int64, state common.ExposureState) {
+	exposed := metav1.Condition{
+		Type:               operatorv1alpha1.ConditionExposed,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             state.Reason,
+		Message:            state.Message,
+	}
+	if state.Ready {
+		exposed.Status = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(&status.Conditions, exposed)
+}
+
+// pvcCondition returns the condition of the pvc with the type when it is true
+func pvcCondition(pvc *corev1.PersistentVolumeClaim, conditionType corev1.PersistentVolumeClaimConditionType) *corev1.PersistentVolumeClaimCondition {
+	for i := range pvc.Status.Conditions {
+		c := &pvc.Status.Conditions[i]
+		if c.Type == conditionType && c.Status == corev1.ConditionTrue {
+			return c
+		}
+	}
+	return nil
+}
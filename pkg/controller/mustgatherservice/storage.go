@@ -0,0 +1,164 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherservice
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// readWriteManyProvisioners are parts of the names of the provisioners of shared file systems,
+// which provision ReadWriteMany volumes
+var readWriteManyProvisioners = []string{
+	"nfs",
+	"cephfs",
+	"glusterfs",
+	"efs.csi.aws.com",
+	"file.csi.azure.com",
+	"kubernetes.io/azure-file",
+	"ibm.io/ibmc-file",
+	"vpc.file.csi.ibm.io",
+	"filestore.csi.storage.gke.io",
+	"spectrumscale.csi.ibm.com",
+}
+
+// storageClassError is the reason why no storage class is selected for a pvc
+type storageClassError struct {
+	reason string
+	err    error
+}
+
+func (e *storageClassError) Error() string {
+	return e.err.Error()
+}
+
+// expansionError is the reason why a pvc is not expanded to the requested storage
+type expansionError struct {
+	reason string
+	err    error
+}
+
+func (e *expansionError) Error() string {
+	return e.err.Error()
+}
+
+// expandMustGatherServicePVC raises the storage request of the pvc to the storage requested by the
+// MustGatherService, when its storage class allows volume expansion. The pvc is updated in place.
+func (r *ReconcileMustGatherService) expandMustGatherServicePVC(instance *operatorv1alpha1.MustGatherService, pvc *corev1.PersistentVolumeClaim) error {
+	requested, ok := instance.Spec.PersistentVolumeClaim.Resources.Requests[corev1.ResourceStorage]
+	if !ok {
+		return nil
+	}
+	current := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	switch requested.Cmp(current) {
+	case 0:
+		return nil
+	case -1:
+		return &expansionError{
+			reason: "ShrinkNotSupported",
+			err:    fmt.Errorf("pvc %s can't be shrunk from %s to %s", pvc.Name, current.String(), requested.String()),
+		}
+	}
+
+	allowed, err := r.volumeExpansionAllowed(pvc)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return &expansionError{
+			reason: "ExpansionNotAllowed",
+			err: fmt.Errorf("storage class %q of pvc %s doesn't allow volume expansion to %s",
+				storageClassOf(pvc), pvc.Name, requested.String()),
+		}
+	}
+
+	log.Info("Expanding pvc", "pvc.Namespace", pvc.Namespace, "pvc.Name", pvc.Name, "from", current.String(), "to", requested.String())
+	updated := pvc.DeepCopy()
+	if updated.Spec.Resources.Requests == nil {
+		updated.Spec.Resources.Requests = corev1.ResourceList{}
+	}
+	updated.Spec.Resources.Requests[corev1.ResourceStorage] = requested
+	if err := r.client.Update(context.TODO(), updated); err != nil {
+		return err
+	}
+	updated.DeepCopyInto(pvc)
+	return nil
+}
+
+// volumeExpansionAllowed returns whether the storage class of the pvc allows volume expansion
+func (r *ReconcileMustGatherService) volumeExpansionAllowed(pvc *corev1.PersistentVolumeClaim) (bool, error) {
+	name := storageClassOf(pvc)
+	if name == "" {
+		return false, nil
+	}
+	sc := &storagev1.StorageClass{}
+	if err := r.reader.Get(context.TODO(), types.NamespacedName{Name: name}, sc); err != nil {
+		return false, err
+	}
+	return sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion, nil
+}
+
+// readWriteManyStorageClass returns whether the pvc of the named storage class is ReadWriteMany,
+// it is when ReadWriteMany is preferred and the class provisions ReadWriteMany volumes
+func (r *ReconcileMustGatherService) readWriteManyStorageClass(pvc *operatorv1alpha1.PersistentVolumeClaim, name string) (bool, error) {
+	if pvc.StorageClassSelection != operatorv1alpha1.StorageClassPreferReadWriteMany {
+		return false, nil
+	}
+	sc := &storagev1.StorageClass{}
+	if err := r.reader.Get(context.TODO(), types.NamespacedName{Name: name}, sc); err != nil {
+		return false, err
+	}
+	return readWriteManyProvisioner(sc.Provisioner), nil
+}
+
+// allowedProvisioner returns whether a storage class of the provisioner can be selected for the pvc
+func allowedProvisioner(pvc *operatorv1alpha1.PersistentVolumeClaim, provisioner string) bool {
+	if len(pvc.AllowedProvisioners) == 0 {
+		return true
+	}
+	for _, p := range pvc.AllowedProvisioners {
+		if p == provisioner {
+			return true
+		}
+	}
+	return false
+}
+
+// readWriteManyProvisioner returns whether the provisioner provisions ReadWriteMany volumes
+func readWriteManyProvisioner(provisioner string) bool {
+	for _, p := range readWriteManyProvisioners {
+		if strings.Contains(provisioner, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// storageClassOf returns the name of the storage class of the pvc
+func storageClassOf(pvc *corev1.PersistentVolumeClaim) string {
+	if pvc.Spec.StorageClassName != nil {
+		return *pvc.Spec.StorageClassName
+	}
+	return ""
+}
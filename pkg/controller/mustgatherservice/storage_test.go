@@ -0,0 +1,110 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mustgatherservice
+
+import (
+	goerrors "errors"
+	"testing"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newStorageClass(name, provisioner string, isDefault bool) *storagev1.StorageClass {
+	sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: name}, Provisioner: provisioner}
+	if isDefault {
+		sc.Annotations = map[string]string{"storageclass.kubernetes.io/is-default-class": "true"}
+	}
+	return sc
+}
+
+func TestDesiredMustGatherServicePVC(t *testing.T) {
+	block := newStorageClass("block", "ebs.csi.aws.com", true)
+	file := newStorageClass("file", "efs.csi.aws.com", false)
+
+	tests := []struct {
+		name           string
+		classes        []runtime.Object
+		pvc            operatorv1alpha1.PersistentVolumeClaim
+		wantClass      string
+		wantAccessMode corev1.PersistentVolumeAccessMode
+		wantReason     string
+	}{
+		{"default class", []runtime.Object{block, file}, operatorv1alpha1.PersistentVolumeClaim{},
+			"block", corev1.ReadWriteOnce, ""},
+		{"prefer ReadWriteMany", []runtime.Object{block, file},
+			operatorv1alpha1.PersistentVolumeClaim{StorageClassSelection: operatorv1alpha1.StorageClassPreferReadWriteMany},
+			"file", corev1.ReadWriteMany, ""},
+		{"ReadWriteMany access mode", []runtime.Object{block, file},
+			operatorv1alpha1.PersistentVolumeClaim{AccessMode: corev1.ReadWriteMany},
+			"file", corev1.ReadWriteMany, ""},
+		{"ReadWriteMany access mode of a named class", []runtime.Object{block, file},
+			operatorv1alpha1.PersistentVolumeClaim{StorageClassName: "block", AccessMode: corev1.ReadWriteMany},
+			"block", corev1.ReadWriteMany, ""},
+		{"allowed provisioners", []runtime.Object{block, file},
+			operatorv1alpha1.PersistentVolumeClaim{AllowedProvisioners: []string{"efs.csi.aws.com"}},
+			"file", corev1.ReadWriteOnce, ""},
+		{"no class of the allowed provisioners", []runtime.Object{block},
+			operatorv1alpha1.PersistentVolumeClaim{AllowedProvisioners: []string{"efs.csi.aws.com"}},
+			"", "", "NoMatchingStorageClass"},
+		{"no class", nil, operatorv1alpha1.PersistentVolumeClaim{}, "", corev1.ReadWriteOnce, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := runtime.NewScheme()
+			if err := storagev1.AddToScheme(s); err != nil {
+				t.Fatal(err)
+			}
+			if err := operatorv1alpha1.SchemeBuilder.AddToScheme(s); err != nil {
+				t.Fatal(err)
+			}
+			c := fake.NewFakeClientWithScheme(s, tt.classes...)
+			r := &ReconcileMustGatherService{client: c, reader: c, scheme: s}
+			instance := &operatorv1alpha1.MustGatherService{
+				ObjectMeta: metav1.ObjectMeta{Name: "must-gather-service", Namespace: "ibm-common-services"},
+			}
+			instance.Spec.PersistentVolumeClaim = tt.pvc
+			instance.Spec.PersistentVolumeClaim.Name = "must-gather-pvc"
+
+			pvc, err := r.desiredMustGatherServicePVC(instance)
+			if tt.wantReason != "" {
+				var scErr *storageClassError
+				if !goerrors.As(err, &scErr) || scErr.reason != tt.wantReason {
+					t.Fatalf("err = %v, want a %s storageClassError", err, tt.wantReason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantClass == "" && pvc.Spec.StorageClassName != nil {
+				t.Errorf("StorageClassName = %q, want nil", *pvc.Spec.StorageClassName)
+			}
+			if got := storageClassOf(pvc); got != tt.wantClass {
+				t.Errorf("StorageClassName = %q, want %q", got, tt.wantClass)
+			}
+			if got := pvc.Spec.AccessModes; len(got) != 1 || got[0] != tt.wantAccessMode {
+				t.Errorf("AccessModes = %v, want [%s]", got, tt.wantAccessMode)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package integrity writes the signed manifest of the files of a must gather archive, and verifies
+// an archive against its manifest
+package integrity
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/IBM/ibm-healthcheck-operator/pkg/artifact"
+)
+
+const (
+	// ManifestSuffix is appended to the archive name to name its manifest
+	ManifestSuffix = ".manifest.json"
+	// SignatureSuffix is appended to the archive name to name the signature of its manifest
+	SignatureSuffix = ".manifest.sig"
+)
+
+// Manifest lists the SHA-256 digests of an archive and of its files
+type Manifest struct {
+	// Archive is the file name of the archive
+	Archive string `json:"archive"`
+	// Digest is the digest of the archive, as sha256:<hex>
+	Digest string `json:"digest"`
+	// Size is the size of the archive in bytes
+	Size int64 `json:"size"`
+	// Created is when the manifest was written, in RFC 3339 format
+	Created string `json:"created"`
+	// Files are the regular files of the archive, in archive order
+	Files []File `json:"files"`
+}
+
+// File is the digest of a file of the archive
+type File struct {
+	// Name is the name of the file in the archive
+	Name string `json:"name"`
+	// Digest is the digest of the file content, as sha256:<hex>
+	Digest string `json:"digest"`
+	// Size is the size of the file in bytes
+	Size int64 `json:"size"`
+}
+
+// ManifestPath returns the path of the manifest of the archive, next to it
+func ManifestPath(archive string) string {
+	return archive + ManifestSuffix
+}
+
+// SignaturePath returns the path of the signature of the manifest of the archive, next to it
+func SignaturePath(archive string) string {
+	return archive + SignatureSuffix
+}
+
+// NewManifest computes the digests of the .tar.gz archive at path and of its regular files
+func NewManifest(path string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	archiveHash := sha256.New()
+	size := &countingWriter{}
+	gr, err := gzip.NewReader(io.TeeReader(f, io.MultiWriter(archiveHash, size)))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a .tar.gz archive: %v", path, err)
+	}
+	m := &Manifest{
+		Archive: filepath.Base(path),
+		Created: time.Now().UTC().Format(time.RFC3339),
+		Files:   []File{},
+	}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		h := sha256.New()
+		n, err := io.Copy(h, tr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", path, hdr.Name, err)
+		}
+		m.Files = append(m.Files, File{Name: hdr.Name, Digest: digest(h.Sum(nil)), Size: n})
+	}
+	// the archive digest covers the bytes after the end of the gzip stream too
+	if _, err := io.Copy(ioutil.Discard, io.TeeReader(f, io.MultiWriter(archiveHash, size))); err != nil {
+		return nil, err
+	}
+	m.Digest = digest(archiveHash.Sum(nil))
+	m.Size = size.n
+	return m, nil
+}
+
+// ArchiveData returns the archive of the data of dir gathered by the job. The data is archived in
+// <job>.tar.gz, and removed, unless it is a single .tar.gz archive already.
+func ArchiveData(dir, job string) (string, error) {
+	names, err := artifact.Find(dir, job)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no gathered data %s in %s", job, dir)
+	}
+	if len(names) == 1 && isTarGz(names[0]) {
+		return filepath.Join(dir, names[0]), nil
+	}
+
+	archive := filepath.Join(dir, job+".tar.gz")
+	tmp, err := ioutil.TempFile(dir, "."+job+".tar.gz-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if err := writeTarGz(tmp, dir, names); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), archive); err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		if path := filepath.Join(dir, name); path != archive {
+			if err := os.RemoveAll(path); err != nil {
+				return "", err
+			}
+		}
+	}
+	return archive, nil
+}
+
+// writeTarGz writes the named entries of dir, with their content, as a .tar.gz archive to w
+func writeTarGz(w io.Writer, dir string, names []string) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, name := range names {
+		err := filepath.Walk(filepath.Join(dir, name), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			link := ""
+			if info.Mode()&os.ModeSymlink != 0 {
+				if link, err = os.Readlink(path); err != nil {
+					return err
+				}
+			}
+			hdr, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			hdr.Name = filepath.ToSlash(rel)
+			if info.IsDir() {
+				hdr.Name += "/"
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+func digest(sum []byte) string {
+	return "sha256:" + hex.EncodeToString(sum)
+}
+
+func isTarGz(name string) bool {
+	return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
@@ -0,0 +1,109 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package integrity
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestArchiveData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "integrity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"job-1/overview.txt":  "cluster overview",
+		"job-1/pods/logs.txt": "pod logs",
+		"job-10/other.txt":    "another run",
+	})
+
+	archive, err := ArchiveData(dir, "job-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "job-1.tar.gz"); archive != want {
+		t.Fatalf("ArchiveData() = %s, want %s", archive, want)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	// the data is removed once archived, the data of other runs is left
+	if want := []string{"job-1.tar.gz", "job-10"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("entries = %v, want %v", names, want)
+	}
+
+	m, err := NewManifest(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, f := range m.Files {
+		files = append(files, f.Name)
+	}
+	if want := []string{"job-1/overview.txt", "job-1/pods/logs.txt"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("manifest files = %v, want %v", files, want)
+	}
+
+	if _, err := ArchiveData(dir, "job-2"); err == nil || !strings.Contains(err.Error(), "no gathered data") {
+		t.Errorf("ArchiveData(job-2) err = %v, want no gathered data", err)
+	}
+}
+
+func TestCompareManifests(t *testing.T) {
+	file := func(name, digest string) File { return File{Name: name, Digest: digest, Size: 1} }
+	signed := &Manifest{Archive: "job-1.tar.gz", Digest: "sha256:a", Files: []File{file("a", "sha256:1"), file("b", "sha256:2")}}
+
+	tests := []struct {
+		name    string
+		actual  *Manifest
+		wantErr string
+	}{
+		{"same", &Manifest{Archive: "job-1.tar.gz", Digest: "sha256:a", Files: []File{file("a", "sha256:1"), file("b", "sha256:2")}}, ""},
+		{"other archive", &Manifest{Archive: "job-2.tar.gz", Digest: "sha256:a"}, "the manifest is for archive job-1.tar.gz"},
+		{"archive digest", &Manifest{Archive: "job-1.tar.gz", Digest: "sha256:b"}, "archive digest sha256:b"},
+		{"tampered file", &Manifest{Archive: "job-1.tar.gz", Digest: "sha256:a", Files: []File{file("a", "sha256:1"), file("b", "sha256:3")}},
+			"file b has digest sha256:3"},
+		{"missing file", &Manifest{Archive: "job-1.tar.gz", Digest: "sha256:a", Files: []File{file("a", "sha256:1")}}, "file b is missing"},
+		{"extra file", &Manifest{Archive: "job-1.tar.gz", Digest: "sha256:a",
+			Files: []File{file("a", "sha256:1"), file("b", "sha256:2"), file("c", "sha256:4")}}, "file c is not in the manifest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := compareManifests(signed, tt.actual)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("compareManifests() err = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("compareManifests() err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package integrity
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// PrivateKeySecretKey is the key of the PEM encoded ed25519 private key in the signing key secret
+const PrivateKeySecretKey = "privateKey"
+
+// Result describes a signed archive
+type Result struct {
+	// Archive is the file name of the archive
+	Archive string `json:"archive"`
+	// Manifest is the file name of the manifest, next to the archive
+	Manifest string `json:"manifest"`
+	// Signature is the file name of the signature of the manifest, next to the archive
+	Signature string `json:"signature"`
+	// Digest is the digest of the archive, as sha256:<hex>
+	Digest string `json:"digest"`
+	// Files is the number of files in the manifest
+	Files int32 `json:"files"`
+	// KeyFingerprint is the fingerprint of the public key the signature is verified with
+	KeyFingerprint string `json:"keyFingerprint"`
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS #8 ed25519 private key, as written by
+// openssl genpkey -algorithm ed25519
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("no PEM encoded PRIVATE KEY")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key is a %T, not an ed25519 key", key)
+	}
+	return edKey, nil
+}
+
+// ParsePublicKey parses a PEM encoded PKIX ed25519 public key, as written by openssl pkey -pubout,
+// or the public key of a PEM encoded private key
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded key")
+	}
+	if block.Type == "PRIVATE KEY" {
+		key, err := ParsePrivateKey(data)
+		if err != nil {
+			return nil, err
+		}
+		return key.Public().(ed25519.PublicKey), nil
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("no PEM encoded PUBLIC KEY")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("the public key is a %T, not an ed25519 key", key)
+	}
+	return edKey, nil
+}
+
+// Fingerprint identifies the public key, as sha256:<hex> of the key bytes
+func Fingerprint(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return digest(sum[:])
+}
+
+// SignArchive writes the manifest of the archive and its signature with key next to the archive
+func SignArchive(archive string, key ed25519.PrivateKey) (*Manifest, error) {
+	m, err := NewManifest(archive)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, '\n')
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)) + "\n"
+
+	if err := ioutil.WriteFile(ManifestPath(archive), data, 0644); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(SignaturePath(archive), []byte(signature), 0644); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Verify checks the signature of the manifest of the archive with key, then checks the archive against
+// the manifest. It returns the verified manifest.
+func Verify(archive string, key ed25519.PublicKey) (*Manifest, error) {
+	data, err := ioutil.ReadFile(ManifestPath(archive))
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := ioutil.ReadFile(SignaturePath(archive))
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature %s: %v", SignaturePath(archive), err)
+	}
+	if !ed25519.Verify(key, data, signature) {
+		return nil, fmt.Errorf("the signature of %s doesn't match key %s", ManifestPath(archive), Fingerprint(key))
+	}
+
+	signed := &Manifest{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(signed); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %v", ManifestPath(archive), err)
+	}
+	actual, err := NewManifest(archive)
+	if err != nil {
+		return nil, err
+	}
+	if err := compareManifests(signed, actual); err != nil {
+		return nil, fmt.Errorf("%s doesn't match its manifest: %v", archive, err)
+	}
+	return signed, nil
+}
+
+// compareManifests returns the first difference of the actual manifest of an archive from the signed one
+func compareManifests(signed, actual *Manifest) error {
+	if signed.Archive != actual.Archive {
+		return fmt.Errorf("the manifest is for archive %s", signed.Archive)
+	}
+	if signed.Digest != actual.Digest {
+		return fmt.Errorf("archive digest %s, expected %s", actual.Digest, signed.Digest)
+	}
+	for i, f := range signed.Files {
+		if i >= len(actual.Files) {
+			return fmt.Errorf("file %s is missing", f.Name)
+		}
+		if actual.Files[i] != f {
+			return fmt.Errorf("file %s has digest %s, expected file %s with digest %s",
+				actual.Files[i].Name, actual.Files[i].Digest, f.Name, f.Digest)
+		}
+	}
+	if len(actual.Files) > len(signed.Files) {
+		return fmt.Errorf("file %s is not in the manifest", actual.Files[len(signed.Files)].Name)
+	}
+	return nil
+}
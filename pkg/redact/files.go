@@ -0,0 +1,179 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package redact
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/IBM/ibm-healthcheck-operator/pkg/artifact"
+)
+
+// RedactData redacts the entries of dir gathered by the job, such as the directory and the archives of
+// the gather. The files of the directories and of the .tar.gz archives are rewritten in place.
+func (r *Redactor) RedactData(dir, job string) (Result, error) {
+	result := Result{}
+	names, err := artifact.Find(dir, job)
+	if err != nil {
+		return result, err
+	}
+	for _, name := range names {
+		res, err := r.RedactPath(filepath.Join(dir, name))
+		result.Add(res)
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// RedactPath redacts the file, the files of the directory or the files of the .tar.gz archive at path
+func (r *Redactor) RedactPath(path string) (Result, error) {
+	result := Result{}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return result, err
+	}
+	if !info.IsDir() {
+		return r.redactFile(path, info)
+	}
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		res, err := r.redactFile(p, info)
+		result.Add(res)
+		return err
+	})
+	return result, err
+}
+
+// redactFile redacts a regular file, or the files of a .tar.gz archive
+func (r *Redactor) redactFile(path string, info os.FileInfo) (Result, error) {
+	if !info.Mode().IsRegular() {
+		return Result{}, nil
+	}
+	if isTarGz(path) {
+		return r.redactTarGz(path, info)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+	redacted, keep, result := r.Redact(path, content)
+	if result.FilesRedacted == 0 {
+		return result, nil
+	}
+	if !keep {
+		return result, os.Remove(path)
+	}
+	return result, replaceFile(path, info.Mode(), func(w io.Writer) error {
+		_, err := w.Write(redacted)
+		return err
+	})
+}
+
+// redactTarGz rewrites the archive with its files redacted, the files which only held resources of
+// the removed kinds are left out
+func (r *Redactor) redactTarGz(path string, info os.FileInfo) (Result, error) {
+	result := Result{}
+	in, err := os.Open(path)
+	if err != nil {
+		return result, err
+	}
+	defer in.Close()
+	gr, err := gzip.NewReader(in)
+	if err != nil {
+		return result, err
+	}
+
+	err = replaceFile(path, info.Mode(), func(w io.Writer) error {
+		gw := gzip.NewWriter(w)
+		tr := tar.NewReader(gr)
+		tw := tar.NewWriter(gw)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				if err := tw.WriteHeader(hdr); err != nil {
+					return err
+				}
+				if _, err := io.Copy(tw, tr); err != nil {
+					return err
+				}
+				continue
+			}
+			content, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			redacted, keep, res := r.Redact(hdr.Name, content)
+			result.Add(res)
+			if !keep {
+				continue
+			}
+			hdr.Size = int64(len(redacted))
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if _, err := tw.Write(redacted); err != nil {
+				return err
+			}
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		return gw.Close()
+	})
+	return result, err
+}
+
+// replaceFile writes the new content of the file at path in a temporary file of the same directory,
+// which then replaces the file
+func replaceFile(path string, mode os.FileMode, write func(io.Writer) error) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".redact-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode.Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func isTarGz(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
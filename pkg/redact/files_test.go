@@ -0,0 +1,183 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package redact
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+)
+
+// writeTarGz writes the files, in order, and a directory entry as a .tar.gz archive at path
+func writeTarGz(t *testing.T, path string, files [][2]string) {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	if err := tw.WriteHeader(&tar.Header{Name: "job-1/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f[0], Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(f[1]))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0640); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readTarGz returns the entries of the .tar.gz archive at path, in order
+func readTarGz(t *testing.T, path string) [][2]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := [][2]string{}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, [2]string{hdr.Name, string(content)})
+	}
+}
+
+func TestRedactTarGz(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "job-1.tar.gz")
+	writeTarGz(t, archive, [][2]string{
+		{"job-1/pod.log", "password=hunter2\n"},
+		{"job-1/secret.yaml", "kind: Secret\n"},
+		{"job-1/cm.json", `{"kind":"ConfigMap","data":{"token":"abc"}}`},
+		{"job-1/bad.yaml", "kind: [\n"},
+		{"job-1/nested.tgz", "\x1f\x8b\x00"},
+	})
+	r := newRedactor(t, &operatorv1alpha1.Redaction{
+		Rules:       []string{"hunter2"},
+		KeyPatterns: []string{"token"},
+		Kinds:       []string{"Secret"},
+	})
+
+	info, err := os.Stat(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := r.redactTarGz(archive, info)
+	if err != nil {
+		t.Fatalf("redactTarGz() error = %v", err)
+	}
+	want := Result{FilesScanned: 3, FilesSkipped: 2, FilesRedacted: 3, RuleMatches: 1, KeysRedacted: 1, ResourcesRemoved: 1}
+	if result != want {
+		t.Errorf("redactTarGz() result = %+v, want %+v", result, want)
+	}
+	wantEntries := [][2]string{
+		{"job-1/", ""},
+		{"job-1/pod.log", "password=REDACTED\n"},
+		{"job-1/cm.json", "{\n  \"data\": {\n    \"token\": \"REDACTED\"\n  },\n  \"kind\": \"ConfigMap\"\n}\n"},
+		{"job-1/bad.yaml", "kind: [\n"},
+		{"job-1/nested.tgz", "\x1f\x8b\x00"},
+	}
+	if got := readTarGz(t, archive); !reflect.DeepEqual(got, wantEntries) {
+		t.Errorf("redactTarGz() archive = %q, want %q", got, wantEntries)
+	}
+	if info, err := os.Stat(archive); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("redactTarGz() archive mode = %v, %v, want 0640", info.Mode(), err)
+	}
+	if names, _ := filepath.Glob(filepath.Join(dir, ".*")); len(names) != 0 {
+		t.Errorf("redactTarGz() left temporary files %v", names)
+	}
+}
+
+func TestRedactData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("job-1/namespaces/cs/secret.yaml", "kind: Secret\n")
+	write("job-1/namespaces/cs/pod.log", "hunter2\n")
+	write("job-10/pod.log", "hunter2\n")
+	writeTarGz(t, filepath.Join(dir, "job-1.tar.gz"), [][2]string{{"job-1/pod.log", "hunter2\n"}})
+
+	r := newRedactor(t, &operatorv1alpha1.Redaction{Rules: []string{"hunter2"}, Kinds: []string{"Secret"}})
+	result, err := r.RedactData(dir, "job-1")
+	if err != nil {
+		t.Fatalf("RedactData() error = %v", err)
+	}
+	want := Result{FilesScanned: 3, FilesRedacted: 3, RuleMatches: 2, ResourcesRemoved: 1}
+	if result != want {
+		t.Errorf("RedactData() result = %+v, want %+v", result, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "job-1/namespaces/cs/secret.yaml")); !os.IsNotExist(err) {
+		t.Errorf("RedactData() kept the removed resource: %v", err)
+	}
+	for name, content := range map[string]string{
+		"job-1/namespaces/cs/pod.log": "REDACTED\n",
+		"job-10/pod.log":              "hunter2\n",
+	} {
+		if got, _ := ioutil.ReadFile(filepath.Join(dir, name)); string(got) != content {
+			t.Errorf("RedactData() %s = %q, want %q", name, got, content)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package redact removes the sensitive data of a redaction policy from the gathered data
+package redact
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// PolicyKey is the key of the redaction policy, as JSON, in the configmap of a MustGatherConfig
+const PolicyKey = "redaction.json"
+
+// DefaultReplacement is the text the redacted data is replaced with when the policy doesn't set it
+const DefaultReplacement = "REDACTED"
+
+// Result counts the data redacted
+type Result struct {
+	FilesScanned     int32 `json:"filesScanned"`
+	FilesSkipped     int32 `json:"filesSkipped"`
+	FilesRedacted    int32 `json:"filesRedacted"`
+	RuleMatches      int32 `json:"ruleMatches"`
+	KeysRedacted     int32 `json:"keysRedacted"`
+	ResourcesRemoved int32 `json:"resourcesRemoved"`
+}
+
+// Add adds the counts of other
+func (r *Result) Add(other Result) {
+	r.FilesScanned += other.FilesScanned
+	r.FilesSkipped += other.FilesSkipped
+	r.FilesRedacted += other.FilesRedacted
+	r.RuleMatches += other.RuleMatches
+	r.KeysRedacted += other.KeysRedacted
+	r.ResourcesRemoved += other.ResourcesRemoved
+}
+
+// Redactor applies a compiled redaction policy
+type Redactor struct {
+	rules       []*regexp.Regexp
+	keyPatterns []*regexp.Regexp
+	kinds       map[string]bool
+	replacement string
+}
+
+// New compiles the redaction policy, an invalid regular expression is an error
+func New(policy *operatorv1alpha1.Redaction) (*Redactor, error) {
+	r := &Redactor{
+		kinds:       map[string]bool{},
+		replacement: policy.Replacement,
+	}
+	if r.replacement == "" {
+		r.replacement = DefaultReplacement
+	}
+	for _, rule := range policy.Rules {
+		re, err := regexp.Compile(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %v", rule, err)
+		}
+		r.rules = append(r.rules, re)
+	}
+	for _, pattern := range policy.KeyPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key pattern %q: %v", pattern, err)
+		}
+		r.keyPatterns = append(r.keyPatterns, re)
+	}
+	for _, kind := range policy.Kinds {
+		r.kinds[kind] = true
+	}
+	return r, nil
+}
+
+// Redact returns the content of the file named name without its sensitive data, and false when
+// the file only held resources of the removed kinds. Binary content, such as a nested archive, can't
+// be redacted: it is returned as is and counted as skipped. A YAML or JSON file which can't be parsed
+// while the policy sets key patterns or kinds is counted as skipped too, only the rules apply to it.
+func (r *Redactor) Redact(name string, content []byte) ([]byte, bool, Result) {
+	result := Result{}
+	if bytes.IndexByte(content, 0) >= 0 {
+		result.FilesSkipped = 1
+		return content, true, result
+	}
+	result.FilesScanned = 1
+
+	redacted := content
+	if r.structured(name) {
+		out, changed, err := r.redactResources(name, content, &result)
+		if err != nil {
+			result.FilesScanned = 0
+			result.FilesSkipped = 1
+		} else if changed {
+			redacted = out
+		}
+		if result.ResourcesRemoved > 0 && len(bytes.TrimSpace(redacted)) == 0 {
+			result.FilesRedacted = 1
+			return nil, false, result
+		}
+	}
+	for _, rule := range r.rules {
+		redacted = rule.ReplaceAllFunc(redacted, func([]byte) []byte {
+			result.RuleMatches++
+			return []byte(r.replacement)
+		})
+	}
+	if result.RuleMatches > 0 || result.KeysRedacted > 0 || result.ResourcesRemoved > 0 {
+		result.FilesRedacted = 1
+	}
+	return redacted, true, result
+}
+
+// structured returns whether the file named name is parsed as resources
+func (r *Redactor) structured(name string) bool {
+	if len(r.keyPatterns) == 0 && len(r.kinds) == 0 {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// redactResources removes the resources of the removed kinds and replaces the values of the redacted
+// keys in the YAML documents or the JSON values of content. A JSON file holds one or more values, such
+// as JSON lines, which are written back one per line. It returns false when nothing is redacted, so
+// that the file is kept as is, and an error when a document or a value can't be parsed.
+func (r *Redactor) redactResources(name string, content []byte, result *Result) ([]byte, bool, error) {
+	isJSON := strings.ToLower(filepath.Ext(name)) == ".json"
+	docs, err := parseResources(content, isJSON)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %v", name, err)
+	}
+
+	counts := Result{}
+	kept := []interface{}{}
+	for _, obj := range docs {
+		if obj = r.redactObject(obj, &counts); obj != nil {
+			kept = append(kept, obj)
+		}
+	}
+	if counts.KeysRedacted == 0 && counts.ResourcesRemoved == 0 {
+		return nil, false, nil
+	}
+
+	out := &bytes.Buffer{}
+	for i, obj := range kept {
+		var data []byte
+		var err error
+		switch {
+		case isJSON && len(docs) == 1:
+			data, err = json.MarshalIndent(obj, "", "  ")
+			data = append(data, '\n')
+		case isJSON:
+			data, err = json.Marshal(obj)
+			data = append(data, '\n')
+		default:
+			data, err = yaml.Marshal(obj)
+			if i > 0 {
+				out.WriteString("---\n")
+			}
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %v", name, err)
+		}
+		out.Write(data)
+	}
+	result.KeysRedacted += counts.KeysRedacted
+	result.ResourcesRemoved += counts.ResourcesRemoved
+	return out.Bytes(), true, nil
+}
+
+// parseResources returns the JSON values, or the non empty YAML documents, of content
+func parseResources(content []byte, isJSON bool) ([]interface{}, error) {
+	docs := []interface{}{}
+	if isJSON {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		for {
+			var obj interface{}
+			err := decoder.Decode(&obj)
+			if err == io.EOF {
+				return docs, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, obj)
+		}
+	}
+
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(content)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		var obj interface{}
+		if err := yaml.Unmarshal(doc, &obj, useNumber); err != nil {
+			return nil, err
+		}
+		// a document of comments only
+		if obj == nil {
+			continue
+		}
+		docs = append(docs, obj)
+	}
+}
+
+// redactObject returns the object without its redacted keys, nil when it is a resource of a removed kind
+func (r *Redactor) redactObject(obj interface{}, result *Result) interface{} {
+	if obj = r.removeKinds(obj, result); obj == nil {
+		return nil
+	}
+	return r.redactValue(obj, result)
+}
+
+// removeKinds returns nil when obj is a resource of a removed kind, else obj without the items of
+// the removed kinds when it is a list
+func (r *Redactor) removeKinds(obj interface{}, result *Result) interface{} {
+	m, ok := obj.(map[string]interface{})
+	if !ok {
+		return obj
+	}
+	if kind, ok := m["kind"].(string); ok && r.kinds[kind] {
+		result.ResourcesRemoved++
+		return nil
+	}
+	if items, ok := m["items"].([]interface{}); ok {
+		kept := []interface{}{}
+		for _, item := range items {
+			if item = r.removeKinds(item, result); item != nil {
+				kept = append(kept, item)
+			}
+		}
+		m["items"] = kept
+	}
+	return m
+}
+
+// redactValue replaces the values of the keys matching a key pattern, at any depth
+func (r *Redactor) redactValue(value interface{}, result *Result) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if child != nil && r.redactedKey(key) {
+				v[key] = r.replacement
+				result.KeysRedacted++
+				continue
+			}
+			v[key] = r.redactValue(child, result)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = r.redactValue(v[i], result)
+		}
+	}
+	return value
+}
+
+// useNumber keeps the numbers of the documents as they are written
+func useNumber(d *json.Decoder) *json.Decoder {
+	d.UseNumber()
+	return d
+}
+
+func (r *Redactor) redactedKey(key string) bool {
+	for _, pattern := range r.keyPatterns {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,274 @@
+//
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	operatorv1alpha1 "github.com/IBM/ibm-healthcheck-operator/pkg/apis/operator/v1alpha1"
+)
+
+func newRedactor(t *testing.T, policy *operatorv1alpha1.Redaction) *Redactor {
+	t.Helper()
+	r, err := New(policy)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return r
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(&operatorv1alpha1.Redaction{Rules: []string{"("}}); err == nil {
+		t.Error("New() with an invalid rule, want an error")
+	}
+	if _, err := New(&operatorv1alpha1.Redaction{KeyPatterns: []string{"["}}); err == nil {
+		t.Error("New() with an invalid key pattern, want an error")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	policy := &operatorv1alpha1.Redaction{
+		Rules:       []string{`[a-z0-9.-]*\.customer\.example\.com`},
+		KeyPatterns: []string{"(?i)password|token"},
+		Kinds:       []string{"Secret"},
+	}
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+		keep    bool
+		result  Result
+	}{
+		{
+			name:    "text file with rule matches",
+			file:    "logs/pod.log",
+			content: "connect to db.customer.example.com and api.customer.example.com\n",
+			want:    "connect to REDACTED and REDACTED\n",
+			keep:    true,
+			result:  Result{FilesScanned: 1, FilesRedacted: 1, RuleMatches: 2},
+		},
+		{
+			name:    "text file without match",
+			file:    "logs/pod.log",
+			content: "nothing to see\n",
+			want:    "nothing to see\n",
+			keep:    true,
+			result:  Result{FilesScanned: 1},
+		},
+		{
+			name:    "key patterns only apply to resources",
+			file:    "logs/pod.log",
+			content: "password: secret\n",
+			want:    "password: secret\n",
+			keep:    true,
+			result:  Result{FilesScanned: 1},
+		},
+		{
+			name:    "binary file",
+			file:    "data/archive.tar.gz",
+			content: "\x1f\x8b\x00\x00password: secret",
+			want:    "\x1f\x8b\x00\x00password: secret",
+			keep:    true,
+			result:  Result{FilesSkipped: 1},
+		},
+		{
+			name:    "nested keys of a resource",
+			file:    "namespaces/cs/configmap.yaml",
+			content: "kind: ConfigMap\ndata:\n  db:\n    host: db.customer.example.com\n    Password: secret\n  replicas: 3\n",
+			want:    "data:\n  db:\n    Password: REDACTED\n    host: REDACTED\n  replicas: 3\nkind: ConfigMap\n",
+			keep:    true,
+			result:  Result{FilesScanned: 1, FilesRedacted: 1, RuleMatches: 1, KeysRedacted: 1},
+		},
+		{
+			name:    "null value of a redacted key is kept",
+			file:    "pod.yaml",
+			content: "kind: Pod\ntoken: null\n",
+			want:    "kind: Pod\ntoken: null\n",
+			keep:    true,
+			result:  Result{FilesScanned: 1},
+		},
+		{
+			name:    "resource of a removed kind",
+			file:    "namespaces/cs/secret.yaml",
+			content: "kind: Secret\ndata:\n  key: dmFsdWU=\n",
+			keep:    false,
+			result:  Result{FilesScanned: 1, FilesRedacted: 1, ResourcesRemoved: 1},
+		},
+		{
+			name:    "items of a removed kind in a list",
+			file:    "namespaces/cs/all.json",
+			content: `{"kind":"List","items":[{"kind":"Secret","data":{"key":"dmFsdWU="}},{"kind":"ConfigMap","data":{"token":"abc","size":12345678901234567890}}]}`,
+			want:    "{\n  \"items\": [\n    {\n      \"data\": {\n        \"size\": 12345678901234567890,\n        \"token\": \"REDACTED\"\n      },\n      \"kind\": \"ConfigMap\"\n    }\n  ],\n  \"kind\": \"List\"\n}\n",
+			keep:    true,
+			result:  Result{FilesScanned: 1, FilesRedacted: 1, KeysRedacted: 1, ResourcesRemoved: 1},
+		},
+		{
+			name:    "multi document YAML",
+			file:    "namespaces/cs/resources.yml",
+			content: "# gathered resources\n---\nkind: Secret\ndata:\n  key: dmFsdWU=\n---\nkind: ConfigMap\ndata:\n  token: abc\n---\n",
+			want:    "data:\n  token: REDACTED\nkind: ConfigMap\n",
+			keep:    true,
+			result:  Result{FilesScanned: 1, FilesRedacted: 1, KeysRedacted: 1, ResourcesRemoved: 1},
+		},
+		{
+			name:    "JSON lines",
+			file:    "events.json",
+			content: "{\"kind\":\"Event\",\"message\":\"ok\"}\n{\"kind\":\"Secret\"}\n{\"kind\":\"Event\",\"token\":\"abc\"}\n",
+			want:    "{\"kind\":\"Event\",\"message\":\"ok\"}\n{\"kind\":\"Event\",\"token\":\"REDACTED\"}\n",
+			keep:    true,
+			result:  Result{FilesScanned: 1, FilesRedacted: 1, KeysRedacted: 1, ResourcesRemoved: 1},
+		},
+		{
+			name:    "YAML stream with a bad document",
+			file:    "resources.yaml",
+			content: "kind: ConfigMap\ndata:\n  token: abc\n---\nkind: [Secret\n",
+			want:    "kind: ConfigMap\ndata:\n  token: abc\n---\nkind: [Secret\n",
+			keep:    true,
+			result:  Result{FilesSkipped: 1},
+		},
+		{
+			name:    "JSON which can't be parsed still gets the rules",
+			file:    "dump.json",
+			content: "{\"password\": \"abc\", \"host\": \"db.customer.example.com\"",
+			want:    "{\"password\": \"abc\", \"host\": \"REDACTED\"",
+			keep:    true,
+			result:  Result{FilesSkipped: 1, FilesRedacted: 1, RuleMatches: 1},
+		},
+		{
+			name:    "resource without redacted data is kept as is",
+			file:    "configmap.yaml",
+			content: "kind: ConfigMap   # as gathered\ndata: {size: 1}\n",
+			want:    "kind: ConfigMap   # as gathered\ndata: {size: 1}\n",
+			keep:    true,
+			result:  Result{FilesScanned: 1},
+		},
+	}
+
+	r := newRedactor(t, policy)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, keep, result := r.Redact(tt.file, []byte(tt.content))
+			if keep != tt.keep {
+				t.Errorf("Redact() keep = %v, want %v", keep, tt.keep)
+			}
+			if keep && string(got) != tt.want {
+				t.Errorf("Redact() = %q, want %q", got, tt.want)
+			}
+			if result != tt.result {
+				t.Errorf("Redact() result = %+v, want %+v", result, tt.result)
+			}
+		})
+	}
+}
+
+func TestRedactWithoutResourcePolicy(t *testing.T) {
+	// without key patterns nor kinds, the YAML and JSON files are plain text
+	r := newRedactor(t, &operatorv1alpha1.Redaction{Rules: []string{"secret"}, Replacement: "***"})
+	got, keep, result := r.Redact("bad.json", []byte(`{"a": "secret"`))
+	if !keep || string(got) != `{"a": "***"` {
+		t.Errorf("Redact() = %q, %v", got, keep)
+	}
+	if want := (Result{FilesScanned: 1, FilesRedacted: 1, RuleMatches: 1}); result != want {
+		t.Errorf("Redact() result = %+v, want %+v", result, want)
+	}
+}
+
+func TestRedactResources(t *testing.T) {
+	r := newRedactor(t, &operatorv1alpha1.Redaction{KeyPatterns: []string{"^token$"}, Kinds: []string{"Secret"}})
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+		changed bool
+		wantErr bool
+		result  Result
+	}{
+		{
+			name:    "nothing redacted",
+			file:    "a.yaml",
+			content: "kind: ConfigMap\n",
+		},
+		{
+			name:    "list items",
+			file:    "a.yaml",
+			content: "kind: SecretList\nitems:\n- kind: Secret\n- kind: Secret\n",
+			want:    "items: []\nkind: SecretList\n",
+			changed: true,
+			result:  Result{ResourcesRemoved: 2},
+		},
+		{
+			name:    "nested keys in lists",
+			file:    "a.yaml",
+			content: "spec:\n  containers:\n  - env:\n    - token: abc\n    - tokens: abc\n",
+			want:    "spec:\n  containers:\n  - env:\n    - token: REDACTED\n    - tokens: abc\n",
+			changed: true,
+			result:  Result{KeysRedacted: 1},
+		},
+		{
+			name:    "multi document YAML",
+			file:    "a.yaml",
+			content: "kind: Secret\n---\ntoken: a\n---\ntoken: b\n",
+			want:    "token: REDACTED\n---\ntoken: REDACTED\n",
+			changed: true,
+			result:  Result{KeysRedacted: 2, ResourcesRemoved: 1},
+		},
+		{
+			name:    "all resources removed",
+			file:    "a.json",
+			content: `{"kind": "Secret"}`,
+			changed: true,
+			result:  Result{ResourcesRemoved: 1},
+		},
+		{
+			name:    "YAML parse failure",
+			file:    "a.yaml",
+			content: "token: a\n---\n: :\n  - [\n",
+			wantErr: true,
+		},
+		{
+			name:    "JSON parse failure",
+			file:    "a.json",
+			content: `{"token": "a"} {`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Result{}
+			got, changed, err := r.redactResources(tt.file, []byte(tt.content), &result)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("redactResources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if changed != tt.changed {
+				t.Errorf("redactResources() changed = %v, want %v", changed, tt.changed)
+			}
+			if string(got) != tt.want {
+				t.Errorf("redactResources() = %q, want %q", got, tt.want)
+			}
+			if result != tt.result {
+				t.Errorf("redactResources() result = %+v, want %+v", result, tt.result)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.file) {
+				t.Errorf("redactResources() error = %v, want the file name", err)
+			}
+		})
+	}
+}